@@ -0,0 +1,962 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// gate holds back Work Units on their way to a pool's workers until they're allowed
+// to run, eg. by capacity or rate. Gates are chained, outermost first, each handing
+// the units it lets through on to the next and the last to the pool's workers.
+// Units wait in the order they were queued without occupying a worker or goroutine.
+type gate interface {
+
+	// enter passes the Work Unit on now, or holds it until it's allowed through
+	enter(wu *workUnit)
+
+	// release frees anything held for the Work Unit once it has been
+	// processed or discarded by the pool.
+	release(wu *workUnit)
+
+	// close cancels the waiting Work Units with err, those queued
+	// afterwards pass straight through so the pool rejects them.
+	close(err error)
+
+	// reopen holds back Work Units again after a pool Reset()
+	reopen()
+}
+
+// newGates returns the gates configured by the options, outermost first, along with the
+// function admitting a Work Unit through them all and on to dispatch. discard is called
+// with the units cancelled while waiting, to free anything held for them by outer gates.
+func newGates(o *options, dispatch, discard func(wu *workUnit)) ([]gate, func(wu *workUnit)) {
+
+	var gates []gate
+
+	admit := dispatch
+
+	// innermost so units are handed to the workers at the limited rate,
+	// even when capacity frees up for several at once
+	if o.rate > 0 {
+		g := newRateGate(o.rate, o.burst, admit, discard)
+		gates = append([]gate{g}, gates...)
+		admit = g.enter
+	}
+
+	if o.adaptiveMax > 0 {
+		g := newAdaptiveGate(o.adaptiveMin, o.adaptiveMax, admit, discard)
+		gates = append([]gate{g}, gates...)
+		admit = g.enter
+	}
+
+	if o.capacity > 0 {
+		g := newCapacityGate(o.capacity, admit, discard)
+		gates = append([]gate{g}, gates...)
+		admit = g.enter
+	}
+
+	// outermost so units waiting on their key hold neither capacity nor tokens
+	if o.keyLimit > 0 {
+		g := newKeyGate(o.keyLimit, admit, discard)
+		gates = append([]gate{g}, gates...)
+		admit = g.enter
+	}
+
+	if o.failures > 0 {
+		g := newBreakerGate(o.failures, o.cooldown, admit, discard)
+		gates = append([]gate{g}, gates...)
+		admit = g.enter
+	}
+
+	// ahead of everything so units are rejected straight away while overloaded,
+	// and their wait includes the time spent in the other gates
+	if o.maxWait > 0 {
+		g := newShedGate(o.maxWait, admit, discard)
+		gates = append([]gate{g}, gates...)
+		admit = g.enter
+		o.shedding = g
+	}
+
+	return gates, admit
+}
+
+var _ gate = new(capacityGate)
+
+// capacityGate holds back Work Units until their weight fits within the pool's capacity,
+// see WithCapacity() and WithWeight(). Units are admitted in the order they were queued
+// so heavy units aren't starved by a stream of lighter ones.
+type capacityGate struct {
+	capacity uint
+	used     uint
+	waiting  unitQueue
+	closed   bool
+	next     func(wu *workUnit)
+	discard  func(wu *workUnit)
+	m        sync.Mutex
+}
+
+func newCapacityGate(capacity uint, next, discard func(wu *workUnit)) *capacityGate {
+	return &capacityGate{
+		capacity: capacity,
+		next:     next,
+		discard:  discard,
+	}
+}
+
+// fits reports whether the weight may be admitted, a unit heavier than the
+// whole capacity is admitted once nothing else is running so it runs alone.
+// NOTE: g.m must be held
+func (g *capacityGate) fits(weight uint) bool {
+	return g.used+weight <= g.capacity || g.used == 0
+}
+
+// enter reserves the Work Unit's weight and passes it on if it fits, otherwise it's
+// held until enough capacity has been released.
+func (g *capacityGate) enter(wu *workUnit) {
+
+	g.m.Lock()
+
+	if !g.closed {
+
+		if g.waiting.len() > 0 || !g.fits(wu.weight) {
+			g.waiting.push(wu)
+			g.m.Unlock()
+			return
+		}
+
+		g.used += wu.weight
+		wu.admitted = true
+	}
+
+	g.m.Unlock()
+
+	g.next(wu)
+}
+
+// release returns the weight of an admitted Work Unit, passing on the waiting units that now fit.
+func (g *capacityGate) release(wu *workUnit) {
+
+	g.m.Lock()
+
+	if !wu.admitted {
+		g.m.Unlock()
+		return
+	}
+
+	wu.admitted = false
+	g.used -= wu.weight
+
+	var ready, cancelled []*workUnit
+
+	for !g.closed {
+
+		w := g.waiting.pop()
+		if w == nil {
+			break
+		}
+
+		// cancelled while waiting, eg. by it's context
+		if w.cancelled.Load() != nil {
+			cancelled = append(cancelled, w)
+			continue
+		}
+
+		if !g.fits(w.weight) {
+			g.waiting.pushFront(w)
+			break
+		}
+
+		g.used += w.weight
+		w.admitted = true
+		ready = append(ready, w)
+	}
+
+	g.m.Unlock()
+
+	for _, w := range cancelled {
+		g.discard(w)
+	}
+
+	for _, w := range ready {
+		g.next(w)
+	}
+}
+
+func (g *capacityGate) close(err error) {
+
+	g.m.Lock()
+
+	g.closed = true
+	waiting := drainQueue(&g.waiting)
+
+	g.m.Unlock()
+
+	for _, wu := range waiting {
+		wu.cancelWithError(err)
+		g.discard(wu)
+	}
+}
+
+func (g *capacityGate) reopen() {
+	g.m.Lock()
+	g.closed = false
+	g.m.Unlock()
+}
+
+var _ gate = new(adaptiveGate)
+
+// adaptiveGate limits how many Work Units are let through at once to a limit it adjusts from
+// their latency and errors, see WithAdaptiveConcurrency(). The limit grows by one each time as
+// many units as it allows complete healthily and is cut by a tenth when a unit fails or takes
+// more than twice the baseline latency, at most once for the units started under a limit.
+type adaptiveGate struct {
+	min      float64
+	max      float64
+	limit    float64
+	inflight uint
+	baseline time.Duration // usual latency, the lowest seen, rising slowly only at the minimum limit
+	cut      time.Time     // when the limit was last cut
+	waiting  unitQueue
+	closed   bool
+	next     func(wu *workUnit)
+	discard  func(wu *workUnit)
+	m        sync.Mutex
+}
+
+func newAdaptiveGate(min, max uint, next, discard func(wu *workUnit)) *adaptiveGate {
+
+	if min < 1 {
+		min = 1
+	}
+
+	if max < min {
+		max = min
+	}
+
+	return &adaptiveGate{
+		min:     float64(min),
+		max:     float64(max),
+		limit:   float64(min),
+		next:    next,
+		discard: discard,
+	}
+}
+
+// enter passes the Work Unit on if below the current limit, otherwise it's held until
+// another unit is released.
+func (g *adaptiveGate) enter(wu *workUnit) {
+
+	g.m.Lock()
+
+	if !g.closed {
+
+		if g.waiting.len() > 0 || g.inflight >= uint(g.limit) {
+			g.waiting.push(wu)
+			g.m.Unlock()
+			return
+		}
+
+		g.inflight++
+		wu.limited = true
+	}
+
+	g.m.Unlock()
+
+	g.next(wu)
+}
+
+// adjust updates the limit from the outcome of a Work Unit that ran.
+// NOTE: g.m must be held
+func (g *adaptiveGate) adjust(wu *workUnit, failed bool) {
+
+	latency := time.Since(wu.attempted)
+
+	// a slowdown is only accepted as the new baseline once it persists
+	// at the minimum limit, there being no lower concurrency to try
+	switch {
+	case g.baseline == 0 || latency < g.baseline:
+		g.baseline = latency
+	case !failed && g.limit == g.min:
+		g.baseline += (latency - g.baseline) / 100
+	}
+
+	if failed || latency > g.baseline*2 {
+
+		// units started before the last cut ran under the old limit
+		// and are already accounted for
+		if wu.attempted.Before(g.cut) {
+			return
+		}
+
+		g.limit = max(g.min, g.limit*0.9)
+		g.cut = time.Now()
+		return
+	}
+
+	// only grow while the limit is actually being reached
+	if float64(g.inflight+1) >= g.limit-1 {
+		g.limit = min(g.max, g.limit+1/g.limit)
+	}
+}
+
+// release frees the Work Unit's slot, adjusting the limit by it's outcome and passing on
+// the waiting units now below it.
+func (g *adaptiveGate) release(wu *workUnit) {
+
+	g.m.Lock()
+
+	if !wu.limited {
+		g.m.Unlock()
+		return
+	}
+
+	wu.limited = false
+	g.inflight--
+
+	if failed, counts := outcome(wu); counts && !wu.attempted.IsZero() {
+		g.adjust(wu, failed)
+	}
+
+	var ready, cancelled []*workUnit
+
+	for !g.closed && g.inflight < uint(g.limit) {
+
+		w := g.waiting.pop()
+		if w == nil {
+			break
+		}
+
+		// cancelled while waiting, eg. by it's context
+		if w.cancelled.Load() != nil {
+			cancelled = append(cancelled, w)
+			continue
+		}
+
+		g.inflight++
+		w.limited = true
+		ready = append(ready, w)
+	}
+
+	g.m.Unlock()
+
+	for _, w := range cancelled {
+		g.discard(w)
+	}
+
+	for _, w := range ready {
+		g.next(w)
+	}
+}
+
+// close cancels the waiting Work Units, the limit learned is kept across a Reset()
+func (g *adaptiveGate) close(err error) {
+
+	g.m.Lock()
+
+	g.closed = true
+	waiting := drainQueue(&g.waiting)
+
+	g.m.Unlock()
+
+	for _, wu := range waiting {
+		wu.cancelWithError(err)
+		g.discard(wu)
+	}
+}
+
+func (g *adaptiveGate) reopen() {
+	g.m.Lock()
+	g.closed = false
+	g.m.Unlock()
+}
+
+var _ gate = new(rateGate)
+
+// rateGate hands Work Units on at a steady rate using a token bucket, see WithRateLimit().
+// Units are passed on in the order they were queued, a timer firing as the next token
+// becomes available while any are waiting.
+type rateGate struct {
+	rate    float64 // tokens per second
+	burst   float64
+	tokens  float64
+	last    time.Time // tokens last refilled
+	waiting unitQueue
+	timer   *time.Timer // armed while units are waiting
+	closed  bool
+	next    func(wu *workUnit)
+	discard func(wu *workUnit)
+	m       sync.Mutex
+}
+
+func newRateGate(rate float64, burst uint, next, discard func(wu *workUnit)) *rateGate {
+	return &rateGate{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		tokens:  float64(max(burst, 1)),
+		last:    time.Now(),
+		next:    next,
+		discard: discard,
+	}
+}
+
+// refill adds the tokens accrued since they were last refilled, up to the burst.
+// NOTE: g.m must be held
+func (g *rateGate) refill() {
+
+	now := time.Now()
+
+	g.tokens = min(g.burst, g.tokens+now.Sub(g.last).Seconds()*g.rate)
+	g.last = now
+}
+
+// schedule arms the timer to fire once the next token is available, if not already armed.
+// NOTE: g.m must be held
+func (g *rateGate) schedule() {
+
+	if g.timer != nil {
+		return
+	}
+
+	wait := time.Duration((1 - g.tokens) / g.rate * float64(time.Second))
+
+	g.timer = time.AfterFunc(wait, g.tick)
+}
+
+// enter passes the Work Unit on if a token is available, otherwise it's held until one is.
+func (g *rateGate) enter(wu *workUnit) {
+
+	g.m.Lock()
+
+	if !g.closed {
+
+		g.refill()
+
+		if g.waiting.len() > 0 || g.tokens < 1 {
+			g.waiting.push(wu)
+			g.schedule()
+			g.m.Unlock()
+			return
+		}
+
+		g.tokens--
+	}
+
+	g.m.Unlock()
+
+	g.next(wu)
+}
+
+// tick passes on the waiting Work Units there are now tokens for
+func (g *rateGate) tick() {
+
+	g.m.Lock()
+
+	g.timer = nil
+
+	if g.closed {
+		g.m.Unlock()
+		return
+	}
+
+	g.refill()
+
+	var ready, cancelled []*workUnit
+
+	for g.tokens >= 1 {
+
+		wu := g.waiting.pop()
+		if wu == nil {
+			break
+		}
+
+		// cancelled while waiting, eg. by it's context
+		if wu.cancelled.Load() != nil {
+			cancelled = append(cancelled, wu)
+			continue
+		}
+
+		g.tokens--
+		ready = append(ready, wu)
+	}
+
+	if g.waiting.len() > 0 {
+		g.schedule()
+	}
+
+	g.m.Unlock()
+
+	for _, wu := range cancelled {
+		g.discard(wu)
+	}
+
+	for _, wu := range ready {
+		g.next(wu)
+	}
+}
+
+// release does nothing, tokens are spent as units are passed on
+func (g *rateGate) release(wu *workUnit) {}
+
+func (g *rateGate) close(err error) {
+
+	g.m.Lock()
+
+	g.closed = true
+
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+
+	waiting := drainQueue(&g.waiting)
+
+	g.m.Unlock()
+
+	for _, wu := range waiting {
+		wu.cancelWithError(err)
+		g.discard(wu)
+	}
+}
+
+func (g *rateGate) reopen() {
+	g.m.Lock()
+	g.closed = false
+	g.m.Unlock()
+}
+
+var _ gate = new(keyGate)
+
+// keyGate limits how many Work Units with the same key are let through at once, see
+// WithKeyLimit() and WithKey(). Each key has it's own queue of waiting units so a busy
+// key doesn't hold up the others.
+type keyGate struct {
+	limit   uint
+	keys    map[string]*keyState
+	closed  bool
+	next    func(wu *workUnit)
+	discard func(wu *workUnit)
+	m       sync.Mutex
+}
+
+// keyState is a key's count of units let through and those waiting, it's
+// removed once neither so the gate only holds the keys in use.
+type keyState struct {
+	running uint
+	waiting unitQueue
+}
+
+func newKeyGate(limit uint, next, discard func(wu *workUnit)) *keyGate {
+	return &keyGate{
+		limit:   limit,
+		keys:    make(map[string]*keyState),
+		next:    next,
+		discard: discard,
+	}
+}
+
+// enter passes the Work Unit on if it has no key or it's key is below the limit,
+// otherwise it's held until another unit with the key is released.
+func (g *keyGate) enter(wu *workUnit) {
+
+	g.m.Lock()
+
+	if !g.closed && len(wu.key) > 0 {
+
+		ks := g.keys[wu.key]
+
+		if ks == nil {
+			ks = new(keyState)
+			g.keys[wu.key] = ks
+		}
+
+		if ks.running >= g.limit {
+			ks.waiting.push(wu)
+			g.m.Unlock()
+			return
+		}
+
+		ks.running++
+		wu.keyed = true
+	}
+
+	g.m.Unlock()
+
+	g.next(wu)
+}
+
+// release frees the Work Unit's slot for it's key, passing on the next unit waiting on the key.
+func (g *keyGate) release(wu *workUnit) {
+
+	g.m.Lock()
+
+	if !wu.keyed {
+		g.m.Unlock()
+		return
+	}
+
+	wu.keyed = false
+
+	ks := g.keys[wu.key]
+	ks.running--
+
+	var ready *workUnit
+	var cancelled []*workUnit
+
+	for !g.closed {
+
+		w := ks.waiting.pop()
+		if w == nil {
+			break
+		}
+
+		// cancelled while waiting, eg. by it's context
+		if w.cancelled.Load() != nil {
+			cancelled = append(cancelled, w)
+			continue
+		}
+
+		ks.running++
+		w.keyed = true
+		ready = w
+		break
+	}
+
+	if ks.running == 0 && ks.waiting.len() == 0 {
+		delete(g.keys, wu.key)
+	}
+
+	g.m.Unlock()
+
+	for _, w := range cancelled {
+		g.discard(w)
+	}
+
+	if ready != nil {
+		g.next(ready)
+	}
+}
+
+func (g *keyGate) close(err error) {
+
+	g.m.Lock()
+
+	g.closed = true
+
+	var waiting []*workUnit
+
+	for key, ks := range g.keys {
+
+		waiting = append(waiting, drainQueue(&ks.waiting)...)
+
+		if ks.running == 0 {
+			delete(g.keys, key)
+		}
+	}
+
+	g.m.Unlock()
+
+	for _, wu := range waiting {
+		wu.cancelWithError(err)
+		g.discard(wu)
+	}
+}
+
+func (g *keyGate) reopen() {
+	g.m.Lock()
+	g.closed = false
+	g.m.Unlock()
+}
+
+var _ gate = new(breakerGate)
+
+// breakerGate fails Work Units straight away while the circuit breaker they're queued
+// under is open, see WithCircuitBreaker() and WithBreaker().
+type breakerGate struct {
+	failures uint
+	cooldown time.Duration
+	breakers map[string]*breaker
+	next     func(wu *workUnit)
+	discard  func(wu *workUnit)
+	m        sync.Mutex
+}
+
+// breaker is the state of a named circuit breaker, closed breakers without any
+// failures are removed so the gate only holds those failing.
+type breaker struct {
+	failures uint      // consecutive
+	until    time.Time // open until, zero while closed
+	trial    *workUnit // let through while half-open
+}
+
+func newBreakerGate(failures uint, cooldown time.Duration, next, discard func(wu *workUnit)) *breakerGate {
+	return &breakerGate{
+		failures: failures,
+		cooldown: cooldown,
+		breakers: make(map[string]*breaker),
+		next:     next,
+		discard:  discard,
+	}
+}
+
+// enter passes the Work Unit on unless it's breaker is open, failing it instead.
+// Once the cooldown has passed the first unit is let through as a trial.
+func (g *breakerGate) enter(wu *workUnit) {
+
+	g.m.Lock()
+
+	if b := g.breakers[wu.breaker]; b != nil && !b.until.IsZero() {
+
+		if b.trial != nil || time.Now().Before(b.until) {
+			until := b.until
+			g.m.Unlock()
+
+			wu.cancelWithError(&CircuitOpenError{Name: wu.breaker, Until: until})
+			g.discard(wu)
+			return
+		}
+
+		b.trial = wu
+	}
+
+	g.m.Unlock()
+
+	g.next(wu)
+}
+
+// release counts the outcome of the Work Unit towards it's breaker
+func (g *breakerGate) release(wu *workUnit) {
+
+	if len(wu.breaker) == 0 {
+		return
+	}
+
+	failed, counts := outcome(wu)
+
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	b := g.breakers[wu.breaker]
+
+	if b != nil && !b.until.IsZero() {
+
+		// outcomes of units let through before the breaker
+		// opened don't count, only the trial's does
+		if b.trial != wu {
+			return
+		}
+
+		b.trial = nil
+
+		switch {
+		case !counts:
+			// let another unit through to try
+		case failed:
+			b.until = time.Now().Add(g.cooldown)
+		default:
+			delete(g.breakers, wu.breaker)
+		}
+
+		return
+	}
+
+	if !counts {
+		return
+	}
+
+	if !failed {
+		delete(g.breakers, wu.breaker)
+		return
+	}
+
+	if b == nil {
+		b = new(breaker)
+		g.breakers[wu.breaker] = b
+	}
+
+	if b.failures++; b.failures >= g.failures {
+		b.until = time.Now().Add(g.cooldown)
+	}
+}
+
+// close does nothing, no units are held and breakers keep their state across a Reset()
+func (g *breakerGate) close(err error) {}
+
+func (g *breakerGate) reopen() {}
+
+var _ gate = new(shedGate)
+
+// shedGate rejects Work Units as they're queued while the pool is overloaded, see
+// WithLoadShedding(). It estimates the wait to run from the backlog of units waiting
+// ahead and how long the pool has been taking to start each of them, as well as how
+// long the most recent unit waited, so that it still rejects units while every worker
+// is stuck and nothing starts at all. Units that have waited too long anyway are
+// dropped by the pools just before running, see start().
+type shedGate struct {
+	maxWait  time.Duration
+	waited   time.Duration // how long the most recent unit waited to run
+	observed time.Time     // when it was to run
+	waiting  uint          // units passed on that haven't started running yet
+	since    time.Time     // when the backlog of waiting units last built up from none
+	started  time.Time     // when a waiting unit last started running
+	gap      time.Duration // average time taken to start each waiting unit
+	next     func(wu *workUnit)
+	discard  func(wu *workUnit)
+	m        sync.Mutex
+}
+
+func newShedGate(maxWait time.Duration, next, discard func(wu *workUnit)) *shedGate {
+	return &shedGate{
+		maxWait: maxWait,
+		next:    next,
+		discard: discard,
+	}
+}
+
+// estimate returns how long a unit queued now is expected to wait to run, being the
+// longer of the most recent wait, only trusted for maxWait after it was observed so
+// the pool recovers once the backlog has cleared, and the backlog ahead of it by the
+// time taken to start each waiting unit, or since one last started when that's longer.
+// NOTE: g.m must be held
+func (g *shedGate) estimate(now time.Time) time.Duration {
+
+	var wait time.Duration
+
+	if now.Sub(g.observed) < g.maxWait {
+		wait = g.waited
+	}
+
+	if g.waiting > 0 {
+		gap := max(g.gap, now.Sub(latest(g.started, g.since)))
+		wait = max(wait, time.Duration(g.waiting)*gap)
+	}
+
+	return wait
+}
+
+// enter passes the Work Unit on unless the expected wait is too long, failing it instead
+func (g *shedGate) enter(wu *workUnit) {
+
+	now := time.Now()
+
+	g.m.Lock()
+
+	if wait := g.estimate(now); wait > g.maxWait {
+		g.m.Unlock()
+		wu.cancelWithError(&OverloadError{Waited: wait, MaxWait: g.maxWait})
+		g.discard(wu)
+		return
+	}
+
+	if g.waiting == 0 {
+		g.since = now
+	}
+
+	g.waiting++
+	wu.shedding = true
+
+	g.m.Unlock()
+
+	g.next(wu)
+}
+
+// start records that the Work Unit has been picked up to run, returning whether it
+// has waited too long to be worth running, see options.shed().
+func (g *shedGate) start(wu *workUnit) bool {
+
+	g.m.Lock()
+
+	if wu.shedding {
+		wu.shedding = false
+		g.waiting--
+
+		// the time it took to get round to the unit, once it was waiting
+		g.gap = (g.gap*7 + wu.started.Sub(latest(g.started, wu.queued))) / 8
+	}
+
+	if wu.started.After(g.started) {
+		g.started = wu.started
+	}
+
+	waited := wu.started.Sub(wu.queued)
+
+	if wu.started.After(g.observed) {
+		g.waited = waited
+		g.observed = wu.started
+	}
+
+	g.m.Unlock()
+
+	return waited > g.maxWait
+}
+
+// release stops counting the Work Unit as waiting if it never started, eg. cancelled
+func (g *shedGate) release(wu *workUnit) {
+
+	g.m.Lock()
+
+	if wu.shedding {
+		wu.shedding = false
+		g.waiting--
+	}
+
+	g.m.Unlock()
+}
+
+// close does nothing, no units are held
+func (g *shedGate) close(err error) {}
+
+// reopen forgets the waits observed before the pool was closed
+func (g *shedGate) reopen() {
+	g.m.Lock()
+	g.waited = 0
+	g.observed = time.Time{}
+	g.started = time.Time{}
+	g.gap = 0
+	g.m.Unlock()
+}
+
+// latest returns the later of the two times
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// outcome returns whether the Work Unit failed and whether that counts as an outcome of
+// it's work at all, units cancelled or rejected by the pool don't.
+func outcome(wu *workUnit) (failed bool, counts bool) {
+
+	var err error
+
+	// only safe to read the error once done, if not it's still being
+	// cancelled and doesn't count either way
+	select {
+	case <-wu.done:
+		err = wu.err
+	default:
+		err = ErrCancelled
+	}
+
+	return err != nil, ownOutcome(err)
+}
+
+// ownOutcome reports whether the error, or lack of one, is the outcome of a Work Unit's
+// own work and not of it being cancelled or rejected by the pool.
+func ownOutcome(err error) bool {
+	return !errors.Is(err, ErrCancelled) && !errors.Is(err, ErrPoolClosed) && !errors.Is(err, ErrCircuitOpen) &&
+		!errors.Is(err, ErrOverloaded) && !errors.Is(err, ErrExpired) && !errors.Is(err, ErrWorkerInit)
+}
+
+// drainQueue removes and returns all Work Units from the queue, in order
+func drainQueue(q *unitQueue) []*workUnit {
+
+	units := make([]*workUnit, 0, q.len())
+
+	for wu := q.pop(); wu != nil; wu = q.pop() {
+		units = append(units, wu)
+	}
+
+	return units
+}
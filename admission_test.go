@@ -0,0 +1,694 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestCapacity(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(8, WithCapacity(10)), New(WithCapacity(10))} {
+
+		var m sync.Mutex
+		var used, peak uint
+
+		weighted := func(weight uint) WorkFunc {
+			return func(wu WorkUnit) (interface{}, error) {
+
+				m.Lock()
+				used += weight
+				peak = max(peak, used)
+				current := used
+				m.Unlock()
+
+				time.Sleep(time.Millisecond * 5)
+
+				m.Lock()
+				used -= weight
+				m.Unlock()
+
+				return current, nil
+			}
+		}
+
+		var units []WorkUnit
+
+		for i := 0; i < 40; i++ {
+			weight := []uint{8, 3, 3, 1}[i%4]
+			units = append(units, pool.Queue(weighted(weight), WithWeight(weight)))
+		}
+
+		// heavier than the whole capacity, runs alone
+		heavy := pool.Queue(weighted(15), WithWeight(15))
+
+		for i := 0; i < 10; i++ {
+			units = append(units, pool.Queue(weighted(1))) // default weight of 1
+		}
+
+		for _, wu := range units {
+			wu.Wait()
+			Equal(t, wu.Error(), nil)
+		}
+
+		heavy.Wait()
+		Equal(t, heavy.Error(), nil)
+		Equal(t, heavy.Value(), uint(15))
+
+		Equal(t, peak <= 15, true)
+
+		m.Lock()
+		peak = 0
+		m.Unlock()
+
+		for i := 0; i < 20; i++ {
+			units[i] = pool.Queue(weighted(4), WithWeight(4))
+		}
+
+		for _, wu := range units[:20] {
+			wu.Wait()
+		}
+
+		Equal(t, peak <= 8, true)
+
+		pool.Close()
+	}
+}
+
+func TestCapacityWaiting(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(4, WithCapacity(2)), New(WithCapacity(2))} {
+
+		release := make(chan struct{})
+
+		blocking := func(wu WorkUnit) (interface{}, error) {
+			<-release
+			return nil, nil
+		}
+
+		running := pool.Queue(blocking, WithWeight(2))
+
+		// waiting for capacity, not holding a worker
+		cancelled := pool.Queue(blocking)
+		waiting := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			return "ran", nil
+		}, WithWeight(0))
+
+		time.Sleep(time.Millisecond * 20)
+
+		cancelled.Cancel()
+		cancelled.Wait()
+		Equal(t, errors.Is(cancelled.Error(), ErrCancelled), true)
+
+		close(release)
+		running.Wait()
+		waiting.Wait()
+
+		Equal(t, waiting.Error(), nil)
+		Equal(t, waiting.Value(), "ran")
+
+		// units still waiting are cancelled on close
+		release = make(chan struct{})
+
+		running = pool.Queue(blocking, WithWeight(2))
+		waiting = pool.Queue(blocking)
+
+		time.Sleep(time.Millisecond * 20)
+
+		pool.Close()
+		waiting.Wait()
+
+		Equal(t, errors.Is(waiting.Error(), ErrPoolClosed), true)
+
+		close(release)
+		running.Wait()
+
+		// capacity is fully available again after a reset
+		pool.Reset()
+
+		wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			return nil, nil
+		}, WithWeight(2))
+		wu.Wait()
+
+		Equal(t, wu.Error(), nil)
+
+		pool.Close()
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(4, WithRateLimit(100, 5)), New(WithRateLimit(100, 5))} {
+
+		start := time.Now()
+
+		var units []WorkUnit
+
+		for i := 0; i < 25; i++ {
+			units = append(units, pool.Queue(func(wu WorkUnit) (interface{}, error) {
+				return time.Since(start), nil
+			}))
+		}
+
+		for _, wu := range units {
+			wu.Wait()
+			Equal(t, wu.Error(), nil)
+		}
+
+		// the burst runs straight away, the remaining 20 at 100 per second
+		Equal(t, units[4].Value().(time.Duration) < time.Millisecond*50, true)
+		Equal(t, units[24].Value().(time.Duration) >= time.Millisecond*150, true)
+		Equal(t, units[24].Value().(time.Duration) < time.Millisecond*500, true)
+
+		for i := 1; i < len(units); i++ {
+			Equal(t, units[i].Value().(time.Duration) >= units[i-1].Value().(time.Duration)-time.Millisecond*10, true)
+		}
+
+		pool.Close()
+	}
+}
+
+func TestRateLimitWaiting(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(4, WithRateLimit(1, 1), WithCapacity(2)), New(WithRateLimit(1, 1), WithCapacity(2))} {
+
+		noop := func(wu WorkUnit) (interface{}, error) {
+			return nil, nil
+		}
+
+		wu := pool.Queue(noop)
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+
+		// waiting for a token while holding capacity, released once cancelled
+		cancelled := pool.Queue(noop, WithWeight(2))
+		cancelled.Cancel()
+
+		time.Sleep(time.Millisecond * 1100) // next token
+
+		wu = pool.Queue(noop, WithWeight(2))
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+
+		// units still waiting are cancelled on close
+		waiting := pool.Queue(noop)
+
+		pool.Close()
+		waiting.Wait()
+
+		Equal(t, errors.Is(waiting.Error(), ErrPoolClosed), true)
+
+		pool.Reset()
+		pool.Close()
+	}
+}
+
+func TestKeyLimit(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(8, WithKeyLimit(2)), New(WithKeyLimit(2))} {
+
+		var m sync.Mutex
+		running := make(map[string]int)
+		peak := make(map[string]int)
+
+		keyed := func(key string) WorkFunc {
+			return func(wu WorkUnit) (interface{}, error) {
+
+				m.Lock()
+				running[key]++
+				peak[key] = max(peak[key], running[key])
+				m.Unlock()
+
+				time.Sleep(time.Millisecond * 5)
+
+				m.Lock()
+				running[key]--
+				m.Unlock()
+
+				return nil, nil
+			}
+		}
+
+		var units []WorkUnit
+
+		for i := 0; i < 30; i++ {
+			key := []string{"a", "b", ""}[i%3]
+			units = append(units, pool.Queue(keyed(key), WithKey(key)))
+		}
+
+		for _, wu := range units {
+			wu.Wait()
+			Equal(t, wu.Error(), nil)
+		}
+
+		Equal(t, peak["a"], 2)
+		Equal(t, peak["b"], 2)
+		Equal(t, peak[""] > 2, true) // units without a key aren't limited
+
+		pool.Close()
+	}
+}
+
+func TestKeyLimitWaiting(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(2, WithKeyLimit(1)), New(WithKeyLimit(1))} {
+
+		release := make(chan struct{})
+
+		blocking := func(wu WorkUnit) (interface{}, error) {
+			<-release
+			return nil, nil
+		}
+
+		running := pool.Queue(blocking, WithKey("a"))
+
+		// waiting on it's key without holding one of the workers
+		waiting := pool.Queue(blocking, WithKey("a"))
+		cancelled := pool.Queue(blocking, WithKey("a"))
+
+		other := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			return "b", nil
+		}, WithKey("b"))
+
+		other.Wait()
+		Equal(t, other.Value(), "b")
+
+		cancelled.Cancel()
+		close(release)
+
+		running.Wait()
+		waiting.Wait()
+		Equal(t, waiting.Error(), nil)
+		Equal(t, errors.Is(cancelled.Error(), ErrCancelled), true)
+
+		// units still waiting are cancelled on close
+		release = make(chan struct{})
+
+		running = pool.Queue(blocking, WithKey("a"))
+		waiting = pool.Queue(blocking, WithKey("a"))
+
+		pool.Close()
+		waiting.Wait()
+
+		Equal(t, errors.Is(waiting.Error(), ErrPoolClosed), true)
+
+		close(release)
+		running.Wait()
+
+		pool.Reset()
+
+		wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			return nil, nil
+		}, WithKey("a"))
+		wu.Wait()
+
+		Equal(t, wu.Error(), nil)
+
+		pool.Close()
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+
+	errDown := errors.New("downstream unavailable")
+
+	for _, pool := range []Pool{NewLimited(4, WithCircuitBreaker(3, time.Millisecond*50)), New(WithCircuitBreaker(3, time.Millisecond*50))} {
+
+		var ran int32
+		var m sync.Mutex
+
+		call := func(err error) WorkFunc {
+			return func(wu WorkUnit) (interface{}, error) {
+				m.Lock()
+				ran++
+				m.Unlock()
+				return nil, err
+			}
+		}
+
+		run := func(fn WorkFunc, opts ...UnitOption) WorkUnit {
+			wu := pool.Queue(fn, opts...)
+			wu.Wait()
+			return wu
+		}
+
+		// a success resets the consecutive failures
+		run(call(errDown), WithBreaker("down"))
+		run(call(errDown), WithBreaker("down"))
+		run(call(nil), WithBreaker("down"))
+		run(call(errDown), WithBreaker("down"))
+		run(call(errDown), WithBreaker("down"))
+
+		Equal(t, run(call(nil), WithBreaker("down")).Error(), nil)
+
+		// opens after 3 consecutive failures
+		for i := 0; i < 3; i++ {
+			Equal(t, run(call(errDown), WithBreaker("down")).Error(), errDown)
+		}
+
+		m.Lock()
+		ran = 0
+		m.Unlock()
+
+		wu := run(call(nil), WithBreaker("down"))
+
+		var open *CircuitOpenError
+		Equal(t, errors.Is(wu.Error(), ErrCircuitOpen), true)
+		Equal(t, errors.As(wu.Error(), &open), true)
+		Equal(t, open.Name, "down")
+		Equal(t, open.Until.After(time.Now()), true)
+		Equal(t, wu.Error().Error(), "ERROR: Circuit 'down' is open")
+		Equal(t, ran, int32(0))
+
+		// other breakers and units without one are unaffected
+		Equal(t, run(call(nil), WithBreaker("up")).Error(), nil)
+		Equal(t, run(call(nil)).Error(), nil)
+
+		// half-opens after the cooldown letting a single trial through
+		time.Sleep(time.Millisecond * 60)
+
+		release := make(chan struct{})
+
+		trial := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			<-release
+			return nil, errDown
+		}, WithBreaker("down"))
+
+		time.Sleep(time.Millisecond * 10)
+		Equal(t, errors.Is(run(call(nil), WithBreaker("down")).Error(), ErrCircuitOpen), true)
+
+		// a failed trial reopens it for another cooldown
+		close(release)
+		trial.Wait()
+
+		Equal(t, errors.Is(run(call(nil), WithBreaker("down")).Error(), ErrCircuitOpen), true)
+
+		time.Sleep(time.Millisecond * 60)
+
+		// a successful trial closes it
+		Equal(t, run(call(nil), WithBreaker("down")).Error(), nil)
+		Equal(t, run(call(nil), WithBreaker("down")).Error(), nil)
+
+		pool.Close()
+	}
+}
+
+// adaptiveLimit returns the pool's current adaptive concurrency limit
+func adaptiveLimit(p Pool) float64 {
+
+	var gates []gate
+
+	switch p := p.(type) {
+	case *limitedPool:
+		gates = p.gates
+	case *unlimitedPool:
+		gates = p.gates
+	}
+
+	for _, g := range gates {
+		if g, ok := g.(*adaptiveGate); ok {
+			g.m.Lock()
+			defer g.m.Unlock()
+			return g.limit
+		}
+	}
+
+	return 0
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(64, WithAdaptiveConcurrency(1, 64)), New(WithAdaptiveConcurrency(1, 64))} {
+
+		var m sync.Mutex
+		var running, peak int
+
+		// a downstream that slows down once more than 10 calls are in flight
+		downstream := func(wu WorkUnit) (interface{}, error) {
+
+			m.Lock()
+			running++
+			current := running
+			m.Unlock()
+
+			time.Sleep(time.Millisecond * time.Duration(2+2*max(0, current-10)))
+
+			m.Lock()
+			running--
+			m.Unlock()
+
+			return current, nil
+		}
+
+		var units []WorkUnit
+
+		for i := 0; i < 1000; i++ {
+			units = append(units, pool.Queue(downstream))
+		}
+
+		for i, wu := range units {
+			wu.Wait()
+			Equal(t, wu.Error(), nil)
+
+			// past the warm up
+			if i > 300 {
+				peak = max(peak, wu.Value().(int))
+			}
+		}
+
+		limit := adaptiveLimit(pool)
+
+		Equal(t, limit >= 5 && limit <= 16, true)
+		Equal(t, peak <= 20, true)
+
+		pool.Close()
+	}
+}
+
+func TestAdaptiveConcurrencyErrors(t *testing.T) {
+
+	errDown := errors.New("downstream unavailable")
+
+	for _, pool := range []Pool{NewLimited(16, WithAdaptiveConcurrency(2, 16)), New(WithAdaptiveConcurrency(2, 16))} {
+
+		noop := func(wu WorkUnit) (interface{}, error) {
+			time.Sleep(time.Millisecond)
+			return nil, nil
+		}
+
+		var units []WorkUnit
+
+		for i := 0; i < 200; i++ {
+			units = append(units, pool.Queue(noop))
+		}
+
+		for _, wu := range units {
+			wu.Wait()
+		}
+
+		grown := adaptiveLimit(pool)
+		Equal(t, grown > 4, true)
+
+		// failures cut the limit down to the minimum
+		for i := 0; i < 50; i++ {
+			pool.Queue(func(wu WorkUnit) (interface{}, error) {
+				return nil, errDown
+			}).Wait()
+		}
+
+		Equal(t, adaptiveLimit(pool), float64(2))
+
+		// units over the limit wait without a worker and are cancelled on close
+		release := make(chan struct{})
+
+		blocking := func(wu WorkUnit) (interface{}, error) {
+			<-release
+			return nil, nil
+		}
+
+		running := []WorkUnit{pool.Queue(blocking), pool.Queue(blocking)}
+		waiting := pool.Queue(blocking)
+
+		time.Sleep(time.Millisecond * 20)
+
+		pool.Close()
+		waiting.Wait()
+
+		Equal(t, errors.Is(waiting.Error(), ErrPoolClosed), true)
+
+		close(release)
+
+		for _, wu := range running {
+			wu.Wait()
+		}
+
+		pool.Reset()
+
+		wu := pool.Queue(noop)
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+
+		pool.Close()
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+
+	maxWait := time.Millisecond * 20
+
+	for _, pool := range []Pool{NewLimited(1, WithLoadShedding(maxWait)), New(WithCapacity(1), WithLoadShedding(maxWait))} {
+
+		var ran int32
+		var m sync.Mutex
+
+		fast := func(wu WorkUnit) (interface{}, error) {
+			m.Lock()
+			ran++
+			m.Unlock()
+			return nil, nil
+		}
+
+		slow := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			time.Sleep(time.Millisecond * 50)
+			return nil, nil
+		})
+
+		var units []WorkUnit
+
+		for i := 0; i < 5; i++ {
+			units = append(units, pool.Queue(fast))
+		}
+
+		slow.Wait()
+		Equal(t, slow.Error(), nil)
+
+		// dropped before running having waited too long behind the slow unit
+		for _, wu := range units {
+			wu.Wait()
+
+			var overload *OverloadError
+			Equal(t, errors.Is(wu.Error(), ErrOverloaded), true)
+			Equal(t, errors.As(wu.Error(), &overload), true)
+			Equal(t, overload.Waited > maxWait, true)
+			Equal(t, overload.MaxWait, maxWait)
+		}
+
+		Equal(t, ran, int32(0))
+
+		// rejected straight away as they're queued while overloaded
+		wu := pool.Queue(fast)
+		wu.Wait()
+		Equal(t, errors.Is(wu.Error(), ErrOverloaded), true)
+		Equal(t, ran, int32(0))
+
+		// accepted again once the backlog has cleared
+		time.Sleep(maxWait + time.Millisecond*5)
+
+		wu = pool.Queue(fast)
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+		Equal(t, ran, int32(1))
+
+		pool.Close()
+	}
+}
+
+func TestLoadSheddingStuck(t *testing.T) {
+
+	maxWait := time.Millisecond * 20
+
+	noop := func(wu WorkUnit) (interface{}, error) {
+		return nil, nil
+	}
+
+	for _, pool := range []Pool{NewLimited(1, WithLoadShedding(maxWait)), New(WithCapacity(1), WithLoadShedding(maxWait))} {
+
+		block := make(chan struct{})
+
+		stuck := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			<-block
+			return nil, nil
+		})
+
+		var units []WorkUnit
+
+		for i := 0; i < 3; i++ {
+			units = append(units, pool.Queue(noop))
+		}
+
+		time.Sleep(maxWait * 2)
+
+		// rejected as it's queued behind the backlog, though no unit has
+		// started since the stuck one, which didn't wait at all
+		wu := pool.Queue(noop)
+
+		select {
+		case <-wu.(*workUnit).done:
+		case <-time.After(time.Second):
+			t.Fatal("accepted while every worker is stuck")
+		}
+
+		var overload *OverloadError
+		Equal(t, errors.As(wu.Error(), &overload), true)
+		Equal(t, overload.Waited > maxWait, true)
+
+		close(block)
+		stuck.Wait()
+
+		for _, wu := range units {
+			wu.Wait()
+			Equal(t, errors.Is(wu.Error(), ErrOverloaded), true)
+		}
+
+		// accepted again once the backlog has cleared
+		time.Sleep(maxWait + time.Millisecond*5)
+
+		wu = pool.Queue(noop)
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+
+		pool.Close()
+	}
+}
+
+func TestLoadSheddingRetries(t *testing.T) {
+
+	maxWait := time.Millisecond * 50
+
+	for _, pool := range []Pool{NewLimited(4, WithLoadShedding(maxWait)), New(WithLoadShedding(maxWait))} {
+
+		// the time spent on earlier attempts isn't a wait to run
+		wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			time.Sleep(time.Millisecond * 40)
+			return nil, errors.New("failed")
+		}, WithRetries(2))
+		wu.Wait()
+
+		Equal(t, wu.Error().Error(), "failed")
+
+		wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			return "ran", nil
+		})
+		wu.Wait()
+
+		Equal(t, wu.Error(), nil)
+		Equal(t, wu.Value(), "ran")
+
+		pool.Close()
+	}
+}
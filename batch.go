@@ -1,6 +1,12 @@
 package pool
 
-import "sync"
+import (
+	"encoding/json"
+	"io"
+	"iter"
+	"sort"
+	"sync"
+)
 
 // Batch contains all information for a batch run of WorkUnits
 type Batch interface {
@@ -8,7 +14,7 @@ type Batch interface {
 	// Queue queues the work to be run in the pool and starts processing immediately
 	// and also retains a reference for Cancellation and outputting to results.
 	// WARNING be sure to call QueueComplete() once all work has been Queued.
-	Queue(fn WorkFunc)
+	Queue(fn WorkFunc, opts ...UnitOption)
 
 	// QueueComplete lets the batch know that there will be no more Work Units Queued
 	// so that it may close the results channels once all work is completed.
@@ -16,19 +22,68 @@ type Batch interface {
 	// but block forever listening for more results.
 	QueueComplete()
 
+	// QueueFrom queues each function received on ch as it arrives, calling
+	// QueueComplete() automatically once ch is closed. It returns immediately,
+	// receiving in it's own goroutine until ch is closed. Functions received once
+	// the batch has been cancelled or completed are discarded, not run, so
+	// senders are never left blocked but ch must still be closed.
+	QueueFrom(ch <-chan WorkFunc)
+
 	// Cancel cancels the Work Units belonging to this Batch
 	Cancel()
 
 	// Results returns a Work Unit result channel that will output all
-	// completed units of work.
+	// completed units of work. Every call returns the same channel, each
+	// unit is output once no matter how many times it's called.
 	Results() <-chan WorkUnit
 
+	// All returns an iterator over the completed units of work and their errors,
+	// an alternative to Results() for use with range over func:
+	//
+	//	for wu, err := range batch.All() {
+	//		...
+	//	}
+	//
+	// Breaking out of the loop early cancels the rest of the batch.
+	// NOTE: QueueComplete() must still be called, as with Results(). The
+	// iterator can only be ranged over once, like Results() it outputs
+	// each unit once so ranging again yields nothing more.
+	All() iter.Seq2[WorkUnit, error]
+
 	// WaitAll is an alternative to Results() where you
 	// may want/need to wait until all work has been
 	// processed, but don't need to check results.
 	// eg. individual units of work may handle their own
 	// errors, logging...
-	WaitAll()
+	// The returned error is a *BatchError combining the
+	// errors of all failed units, or nil if none failed.
+	// It's safe to call more than once, eg. after Results().
+	WaitAll() error
+
+	// Errors returns a *UnitError for each completed unit of work
+	// that failed, in the order they were Queued.
+	Errors() []error
+
+	// Checkpoint writes a record of the Work Units, identified by their WithID()
+	// identifier, that have completed successfully along with their results, in
+	// order of ID. Each call writes the whole record, including resumed units.
+	// Units without an ID and Units that errored are not recorded.
+	Checkpoint(w io.Writer) error
+
+	// Resume loads a record written by Checkpoint() so that Work Units queued
+	// afterwards with an ID recorded as completed are skipped instead of run.
+	// Skipped units aren't output by Results(), see Resumed() for their results.
+	// NOTE: Resume should be called before any Work Units are Queued.
+	Resume(r io.Reader) error
+
+	// Resumed returns the results loaded by Resume(), by ID, as recorded by Checkpoint().
+	Resumed() map[string]json.RawMessage
+}
+
+// checkpointEntry is a single completed Work Unit as written by Checkpoint()
+type checkpointEntry struct {
+	ID    string      `json:"id"`
+	Value interface{} `json:"value,omitempty"`
 }
 
 // batch contains all information for a batch run of WorkUnits
@@ -40,22 +95,41 @@ type batch struct {
 	done    chan struct{}
 	closed  bool
 	wg      *sync.WaitGroup
+
+	// closed when results are no longer being read, see All(),
+	// so the goroutines sending them aren't left blocked
+	abandoned chan struct{}
+	abandon   sync.Once
+
+	// starts the goroutine closing results once all work is done, see Results()
+	closer sync.Once
+
+	// completed Work Unit results by ID and errors of failed Work Units,
+	// guarded by their own lock as b.m is held by Queue() and Cancel() while
+	// queueing and cancelling units on the pool.
+	completed map[string]interface{}
+	resumed   map[string]json.RawMessage
+	errs      []error
+	cm        sync.Mutex
 }
 
 func newBatch(p Pool) Batch {
 	return &batch{
-		pool:    p,
-		units:   make([]WorkUnit, 0, 4), // capacity it to 4 so it doesn't grow and allocate too many times.
-		results: make(chan WorkUnit),
-		done:    make(chan struct{}),
-		wg:      new(sync.WaitGroup),
+		pool:      p,
+		units:     make([]WorkUnit, 0, 4), // capacity it to 4 so it doesn't grow and allocate too many times.
+		results:   make(chan WorkUnit),
+		done:      make(chan struct{}),
+		abandoned: make(chan struct{}),
+		wg:        new(sync.WaitGroup),
+		completed: make(map[string]interface{}),
+		resumed:   make(map[string]json.RawMessage),
 	}
 }
 
 // Queue queues the work to be run in the pool and starts processing immediately
 // and also retains a reference for Cancellation and outputting to results.
 // WARNING be sure to call QueueComplete() once all work has been Queued.
-func (b *batch) Queue(fn WorkFunc) {
+func (b *batch) Queue(fn WorkFunc, opts ...UnitOption) {
 
 	b.m.Lock()
 
@@ -64,7 +138,21 @@ func (b *batch) Queue(fn WorkFunc) {
 		return
 	}
 
-	wu := b.pool.Queue(fn)
+	if id := unitID(opts); len(id) > 0 {
+
+		b.cm.Lock()
+		_, ok := b.completed[id]
+		b.cm.Unlock()
+
+		// already completed in a previous run, see Resume()
+		if ok {
+			b.m.Unlock()
+			return
+		}
+	}
+
+	wu := b.pool.Queue(fn, opts...)
+	index := len(b.units)
 
 	b.units = append(b.units, wu) // keeping a reference for cancellation purposes
 	b.wg.Add(1)
@@ -72,7 +160,20 @@ func (b *batch) Queue(fn WorkFunc) {
 
 	go func(b *batch, wu WorkUnit) {
 		wu.Wait()
-		b.results <- wu
+
+		b.cm.Lock()
+		if err := wu.Error(); err != nil {
+			b.errs = append(b.errs, &UnitError{Index: index, ID: wu.ID(), Err: err})
+		} else if id := wu.ID(); len(id) > 0 {
+			b.completed[id] = wu.Value()
+		}
+		b.cm.Unlock()
+
+		select {
+		case b.results <- wu:
+		case <-b.abandoned:
+		}
+
 		b.wg.Done()
 	}(b, wu)
 }
@@ -83,11 +184,32 @@ func (b *batch) Queue(fn WorkFunc) {
 // but block forever listening for more results.
 func (b *batch) QueueComplete() {
 	b.m.Lock()
-	b.closed = true
-	close(b.done)
+
+	// may have already been called by Cancel()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+
 	b.m.Unlock()
 }
 
+// QueueFrom queues each function received on ch as it arrives, calling
+// QueueComplete() automatically once ch is closed.
+func (b *batch) QueueFrom(ch <-chan WorkFunc) {
+
+	go func(b *batch) {
+
+		// keeps receiving until ch is closed, even once the batch has been cancelled or
+		// completed, when Queue() discards them, so that senders are never left blocked.
+		for fn := range ch {
+			b.Queue(fn)
+		}
+
+		b.QueueComplete()
+	}(b)
+}
+
 // Cancel cancels the Work Units belonging to this Batch
 func (b *batch) Cancel() {
 
@@ -97,7 +219,13 @@ func (b *batch) Cancel() {
 
 	// go in reverse order to try and cancel as many as possbile
 	// one at end are less likely to have run than those at the beginning
+	err := &CancelledError{Cause: &BatchCancelledError{}}
+
 	for i := len(b.units) - 1; i >= 0; i-- {
+		if wu, ok := b.units[i].(*workUnit); ok {
+			wu.cancelWithError(err)
+			continue
+		}
 		b.units[i].Cancel()
 	}
 
@@ -108,24 +236,142 @@ func (b *batch) Cancel() {
 // completed units of work.
 func (b *batch) Results() <-chan WorkUnit {
 
-	go func(b *batch) {
-		<-b.done
-		b.m.Lock()
-		b.wg.Wait()
-		b.m.Unlock()
-		close(b.results)
-	}(b)
+	b.closer.Do(func() {
+		go func(b *batch) {
+			<-b.done
+
+			// done is closed while holding b.m, so every b.wg.Add() made by
+			// Queue() has happened and the lock needn't be held while waiting,
+			// which would block Cancel() while results are still being read.
+			b.wg.Wait()
+			close(b.results)
+		}(b)
+	})
 
 	return b.results
 }
 
+// All returns an iterator over the completed units of work and their errors,
+// breaking out of the loop early cancels the rest of the batch.
+func (b *batch) All() iter.Seq2[WorkUnit, error] {
+
+	return func(yield func(WorkUnit, error) bool) {
+
+		for wu := range b.Results() {
+			if !yield(wu, wu.Error()) {
+				b.Cancel()
+				b.abandon.Do(func() { close(b.abandoned) })
+				return
+			}
+		}
+	}
+}
+
 // WaitAll is an alternative to Results() where you
 // may want/need to wait until all work has been
 // processed, but don't need to check results.
 // eg. individual units of work may handle their own
 // errors and logging...
-func (b *batch) WaitAll() {
+// The returned error is a *BatchError combining the
+// errors of all failed units, or nil if none failed.
+func (b *batch) WaitAll() error {
 
 	for range b.Results() {
 	}
+
+	if errs := b.Errors(); len(errs) > 0 {
+		return &BatchError{Errors: errs}
+	}
+
+	return nil
+}
+
+// Errors returns a *UnitError for each completed unit of work
+// that failed, in the order they were Queued.
+func (b *batch) Errors() []error {
+
+	b.cm.Lock()
+	errs := make([]error, len(b.errs))
+	copy(errs, b.errs)
+	b.cm.Unlock()
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].(*UnitError).Index < errs[j].(*UnitError).Index
+	})
+
+	return errs
+}
+
+// Checkpoint writes a record of the Work Units, identified by their WithID()
+// identifier, that have completed successfully along with their results, in
+// order of ID. Each call writes the whole record, including resumed units.
+// Units without an ID and Units that errored are not recorded.
+func (b *batch) Checkpoint(w io.Writer) error {
+
+	b.cm.Lock()
+	defer b.cm.Unlock()
+
+	ids := make([]string, 0, len(b.completed))
+
+	for id := range b.completed {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	enc := json.NewEncoder(w)
+
+	for _, id := range ids {
+		if err := enc.Encode(checkpointEntry{ID: id, Value: b.completed[id]}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Resume loads a record written by Checkpoint() so that Work Units queued
+// afterwards with an ID recorded as completed are skipped instead of run.
+// Skipped units aren't output by Results(), see Resumed() for their results.
+// Resumed entries are carried over into any subsequent Checkpoint() with
+// their results as json.RawMessage.
+// NOTE: Resume should be called before any Work Units are Queued.
+func (b *batch) Resume(r io.Reader) error {
+
+	dec := json.NewDecoder(r)
+
+	b.cm.Lock()
+	defer b.cm.Unlock()
+
+	for {
+		var e struct {
+			ID    string          `json:"id"`
+			Value json.RawMessage `json:"value,omitempty"`
+		}
+
+		if err := dec.Decode(&e); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		b.completed[e.ID] = e.Value
+		b.resumed[e.ID] = e.Value
+	}
+}
+
+// Resumed returns the results loaded by Resume(), by ID, as recorded by Checkpoint().
+func (b *batch) Resumed() map[string]json.RawMessage {
+
+	b.cm.Lock()
+	defer b.cm.Unlock()
+
+	resumed := make(map[string]json.RawMessage, len(b.resumed))
+
+	for id, v := range b.resumed {
+		resumed[id] = v
+	}
+
+	return resumed
 }
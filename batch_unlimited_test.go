@@ -1,7 +1,13 @@
 package pool
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -166,7 +172,273 @@ func TestUnlimitedBatchWaitAll(t *testing.T) {
 		batch.QueueComplete()
 	}()
 
+	Equal(t, batch.WaitAll(), nil)
+	Equal(t, count, 10)
+
+	// must not panic closing the results channel again
+	Equal(t, batch.WaitAll(), nil)
+}
+
+func TestUnlimitedBatchCheckpointResume(t *testing.T) {
+
+	var runs int
+	var m sync.Mutex
+
+	newFunc := func(i int, fail bool) WorkFunc {
+		return func(WorkUnit) (interface{}, error) {
+			m.Lock()
+			runs++
+			m.Unlock()
+
+			if fail {
+				return nil, errors.New("nightly job failure")
+			}
+			return i, nil
+		}
+	}
+
+	pool := New()
+	defer pool.Close()
+
+	batch := pool.Batch()
+
+	for i := 0; i < 10; i++ {
+		batch.Queue(newFunc(i, i >= 8), WithID(fmt.Sprintf("unit-%d", i)))
+	}
+
+	batch.Queue(newFunc(10, false)) // no ID, never recorded
+	batch.QueueComplete()
 	batch.WaitAll()
 
-	Equal(t, count, 10)
+	Equal(t, runs, 11)
+
+	var buff bytes.Buffer
+
+	err := batch.Checkpoint(&buff)
+	Equal(t, err, nil)
+
+	runs = 0
+
+	batch = pool.Batch()
+	err = batch.Resume(&buff)
+	Equal(t, err, nil)
+
+	for i := 0; i < 10; i++ {
+		batch.Queue(newFunc(i, false), WithID(fmt.Sprintf("unit-%d", i)))
+	}
+
+	batch.QueueComplete()
+
+	var count int
+
+	for wu := range batch.Results() {
+		Equal(t, wu.Error(), nil)
+		count++
+	}
+
+	Equal(t, count, 2)
+	Equal(t, runs, 2)
+
+	// the results of the skipped units are available
+	resumed := batch.Resumed()
+	Equal(t, len(resumed), 8)
+	Equal(t, string(resumed["unit-3"]), "3")
+
+	// resumed entries are carried over to the next checkpoint, in order of ID
+	buff.Reset()
+
+	err = batch.Checkpoint(&buff)
+	Equal(t, err, nil)
+	Equal(t, bytes.Count(buff.Bytes(), []byte("\n")), 10)
+	Equal(t, strings.HasPrefix(buff.String(), `{"id":"unit-0","value":0}`+"\n"+`{"id":"unit-1","value":1}`), true)
+
+	var again bytes.Buffer
+
+	err = batch.Checkpoint(&again)
+	Equal(t, err, nil)
+	Equal(t, again.String(), buff.String())
+
+	err = pool.Batch().Resume(bytes.NewBufferString("{bad json"))
+	NotEqual(t, err, nil)
+}
+
+func TestUnlimitedBatchErrors(t *testing.T) {
+
+	errOdd := errors.New("odd unit")
+
+	newFunc := func(i int) WorkFunc {
+		return func(WorkUnit) (interface{}, error) {
+			if i%2 == 1 {
+				return nil, errOdd
+			}
+			return i, nil
+		}
+	}
+
+	pool := New()
+	defer pool.Close()
+
+	batch := pool.Batch()
+
+	for i := 0; i < 6; i++ {
+		if i == 3 {
+			batch.Queue(newFunc(i), WithID("three"))
+			continue
+		}
+		batch.Queue(newFunc(i))
+	}
+
+	batch.QueueComplete()
+
+	err := batch.WaitAll()
+	NotEqual(t, err, nil)
+	Equal(t, errors.Is(err, errOdd), true)
+	Equal(t, err.Error(), "Work Unit #1 failed: odd unit\nWork Unit #3 'three' failed: odd unit\nWork Unit #5 failed: odd unit")
+
+	var be *BatchError
+	Equal(t, errors.As(err, &be), true)
+	Equal(t, len(be.Errors), 3)
+
+	errs := batch.Errors()
+	Equal(t, len(errs), 3)
+
+	var ue *UnitError
+	Equal(t, errors.As(errs[1], &ue), true)
+	Equal(t, ue.Index, 3)
+	Equal(t, ue.ID, "three")
+	Equal(t, ue.Err, errOdd)
+
+	// plays nicely with errors.Join
+	joined := errors.Join(errs...)
+	Equal(t, errors.Is(joined, errOdd), true)
+
+	batch = pool.Batch()
+	batch.Queue(newFunc(0))
+	batch.QueueComplete()
+
+	err = batch.WaitAll()
+	Equal(t, err, nil)
+	Equal(t, len(batch.Errors()), 0)
+}
+
+func TestUnlimitedBatchQueueFrom(t *testing.T) {
+
+	newFunc := func(i int) WorkFunc {
+		return func(WorkUnit) (interface{}, error) {
+			return i, nil
+		}
+	}
+
+	pool := New()
+	defer pool.Close()
+
+	ch := make(chan WorkFunc)
+
+	batch := pool.Batch()
+	batch.QueueFrom(ch)
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			ch <- newFunc(i)
+		}
+		close(ch) // completes the batch
+	}()
+
+	var sum int
+
+	for wu := range batch.Results() {
+		Equal(t, wu.Error(), nil)
+		sum += wu.Value().(int)
+	}
+
+	Equal(t, sum, 45)
+
+	// keeps receiving once cancelled, discarding what's sent so the sender isn't left blocked
+	ch = make(chan WorkFunc)
+
+	batch = pool.Batch()
+	batch.QueueFrom(ch)
+
+	ch <- newFunc(0)
+	batch.Cancel()
+
+	for range batch.Results() {
+	}
+
+	var ran int32
+
+	select {
+	case ch <- func(WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&ran, 1)
+		return nil, nil
+	}:
+	case <-time.After(time.Second):
+		t.Fatal("sender blocked after cancel")
+	}
+
+	close(ch)
+	time.Sleep(time.Millisecond * 50)
+
+	Equal(t, atomic.LoadInt32(&ran), int32(0))
+}
+
+func TestUnlimitedBatchAll(t *testing.T) {
+
+	newFunc := func(i int) WorkFunc {
+		return func(WorkUnit) (interface{}, error) {
+			time.Sleep(time.Millisecond)
+			return i, nil
+		}
+	}
+
+	pool := New(WithIdleTimeout(0)) // so idle goroutines aren't kept for reuse
+	defer pool.Close()
+
+	batch := pool.Batch()
+
+	for i := 0; i < 10; i++ {
+		batch.Queue(newFunc(i))
+	}
+
+	batch.QueueComplete()
+
+	var sum int
+
+	for wu, err := range batch.All() {
+		Equal(t, err, nil)
+		sum += wu.Value().(int)
+	}
+
+	Equal(t, sum, 45)
+
+	// each unit is only output once, ranging again must not panic
+	for range batch.All() {
+		sum++
+	}
+
+	Equal(t, sum, 45)
+	Equal(t, batch.WaitAll(), nil)
+
+	// breaking early cancels the rest and releases their goroutines
+	before := runtime.NumGoroutine()
+
+	batch = pool.Batch()
+
+	for i := 0; i < 100; i++ {
+		batch.Queue(newFunc(i))
+	}
+
+	batch.QueueComplete()
+
+	var count int
+
+	for range batch.All() {
+		count++
+		break
+	}
+
+	Equal(t, count, 1)
+
+	time.Sleep(time.Millisecond * 200)
+	Equal(t, runtime.NumGoroutine() <= before, true)
 }
@@ -0,0 +1,197 @@
+package pool
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetterHandler is the function type called with each Work Unit that permanently
+// failed, see WithDeadLetter().
+type DeadLetterHandler func(dl *DeadLetter)
+
+// DeadLetter describes a Work Unit that permanently failed, after all it's retries or
+// by panicking, along with the history of it's attempts.
+type DeadLetter struct {
+
+	// ID is the ID of the Work Unit, if any, see WithID()
+	ID string `json:"id,omitempty"`
+
+	// Task is the Task the Work Unit was queued for by Registry.Queue(), if any, it's
+	// nil for units queued as a WorkFunc which can't be replayed
+	Task *Task `json:"task,omitempty"`
+
+	// Queued is when the Work Unit was queued
+	Queued time.Time `json:"queued"`
+
+	// Attempts are the Work Unit's attempts in order, the last being it's final error
+	Attempts []Attempt `json:"attempts"`
+
+	// Err is the Work Unit's final error, eg. a RecoveryError if it panicked
+	Err error `json:"-"`
+}
+
+// Attempt is a single failed attempt at running a Work Unit
+type Attempt struct {
+
+	// Started is when the attempt started running
+	Started time.Time `json:"started"`
+
+	// Finished is when the attempt failed
+	Finished time.Time `json:"finished"`
+
+	// Error is the attempt's error message, it's Err isn't kept when persisted
+	Error string `json:"error"`
+
+	// Err is the attempt's error
+	Err error `json:"-"`
+}
+
+func newAttempt(started time.Time, err error) Attempt {
+	return Attempt{
+		Started:  started,
+		Finished: time.Now(),
+		Error:    err.Error(),
+		Err:      err,
+	}
+}
+
+// newDeadLetter returns the DeadLetter of a failed Work Unit
+// NOTE: must only be called once the unit is done
+func newDeadLetter(wu *workUnit) *DeadLetter {
+
+	attempts := make([]Attempt, 0, len(wu.failures)+1)
+	attempts = append(attempts, wu.failures...)
+	attempts = append(attempts, newAttempt(wu.attempted, wu.err))
+
+	return &DeadLetter{
+		ID:       wu.id,
+		Task:     wu.task,
+		Queued:   wu.queued,
+		Attempts: attempts,
+		Err:      wu.err,
+	}
+}
+
+// DeadLetterFile is a DeadLetterHandler sink appending each DeadLetter to a file as
+// a line of JSON, see ReplayDeadLetters() to queue them again.
+type DeadLetterFile struct {
+	f   *os.File
+	err error
+	m   sync.Mutex
+}
+
+// NewDeadLetterFile opens the file at path, creating it if need be, to append dead
+// letters to eg. pool.WithDeadLetter(f.Handle)
+func NewDeadLetterFile(path string) (*DeadLetterFile, error) {
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetterFile{f: f}, nil
+}
+
+// Handle appends the DeadLetter to the file, the first error doing so
+// is kept and returned by Close.
+func (d *DeadLetterFile) Handle(dl *DeadLetter) {
+
+	b, err := json.Marshal(dl)
+	if err == nil {
+		b = append(b, '\n')
+	}
+
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if err == nil {
+		_, err = d.f.Write(b)
+	}
+
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+}
+
+// Close closes the file returning the first error writing to it, if any
+func (d *DeadLetterFile) Close() error {
+
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if err := d.f.Close(); err != nil && d.err == nil {
+		d.err = err
+	}
+
+	return d.err
+}
+
+// ReadDeadLetters reads the dead letters appended to the file at path by a DeadLetterFile
+func ReadDeadLetters(path string) ([]*DeadLetter, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var letters []*DeadLetter
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024) // allow for large Task payloads
+
+	for line := 1; scanner.Scan(); line++ {
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		dl := new(DeadLetter)
+
+		if err := json.Unmarshal(scanner.Bytes(), dl); err != nil {
+			return nil, fmt.Errorf("invalid dead letter on line %d of '%s': %w", line, path, err)
+		}
+
+		letters = append(letters, dl)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return letters, nil
+}
+
+// ReplayDeadLetters queues the Task of each dead letter in the file at path onto the Pool
+// again, with it's original ID, returning the queued Work Units. Dead letters of units
+// queued as a WorkFunc, without a Task, can't be replayed and are skipped.
+func ReplayDeadLetters(path string, r *Registry, p Pool, opts ...UnitOption) ([]WorkUnit, error) {
+
+	letters, err := ReadDeadLetters(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []WorkUnit
+
+	for _, dl := range letters {
+
+		if dl.Task == nil {
+			continue
+		}
+
+		unitOpts := opts
+
+		if len(dl.ID) > 0 {
+			unitOpts = append([]UnitOption{WithID(dl.ID)}, opts...)
+		}
+
+		units = append(units, r.Queue(p, *dl.Task, unitOpts...))
+	}
+
+	return units, nil
+}
@@ -0,0 +1,188 @@
+package pool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestDeadLetter(t *testing.T) {
+
+	errFlaky := errors.New("flaky")
+
+	var m sync.Mutex
+	var letters []*DeadLetter
+
+	handler := func(dl *DeadLetter) {
+		m.Lock()
+		letters = append(letters, dl)
+		m.Unlock()
+	}
+
+	for _, pool := range []Pool{NewLimited(2, WithDeadLetter(handler)), New(WithDeadLetter(handler))} {
+
+		letters = nil
+
+		// succeeds on it's third attempt
+		var attempts int
+
+		wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			if attempts++; attempts < 3 {
+				return nil, errFlaky
+			}
+			return attempts, nil
+		}, WithRetries(2))
+		wu.Wait()
+
+		Equal(t, wu.Error(), nil)
+		Equal(t, wu.Value(), 3)
+
+		// fails after all it's retries
+		attempts = 0
+
+		wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			attempts++
+			return nil, errFlaky
+		}, WithRetries(2), WithID("failed"))
+		wu.Wait()
+
+		Equal(t, wu.Error(), errFlaky)
+		Equal(t, attempts, 3)
+
+		// panics aren't retried
+		attempts = 0
+
+		wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			attempts++
+			panic("boom")
+		}, WithRetries(2), WithID("panicked"))
+		wu.Wait()
+
+		Equal(t, errors.Is(wu.Error(), ErrRecovery), true)
+		Equal(t, attempts, 1)
+
+		// cancelled units didn't fail
+		wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			return nil, nil
+		})
+		wu.Cancel()
+		wu.Wait()
+
+		pool.Close()
+
+		Equal(t, len(letters), 2)
+
+		dl := letters[0]
+		Equal(t, dl.ID, "failed")
+		Equal(t, dl.Err, errFlaky)
+		Equal(t, len(dl.Attempts), 3)
+		Equal(t, dl.Task == nil, true)
+
+		for i, a := range dl.Attempts {
+			Equal(t, a.Err, errFlaky)
+			Equal(t, a.Error, "flaky")
+			Equal(t, a.Started.Before(a.Finished), true)
+			Equal(t, a.Started.Before(dl.Queued), false)
+
+			if i > 0 {
+				Equal(t, a.Started.Before(dl.Attempts[i-1].Finished), false)
+			}
+		}
+
+		dl = letters[1]
+		Equal(t, dl.ID, "panicked")
+		Equal(t, errors.Is(dl.Err, ErrRecovery), true)
+		Equal(t, len(dl.Attempts), 1)
+	}
+}
+
+func TestDeadLetterFileReplay(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+
+	sink, err := NewDeadLetterFile(path)
+	Equal(t, err, nil)
+
+	registry := NewRegistry()
+
+	var m sync.Mutex
+	healthy := false
+
+	registry.Register("send", func(wu WorkUnit, payload []byte) (interface{}, error) {
+		m.Lock()
+		defer m.Unlock()
+
+		if !healthy {
+			return nil, errors.New("downstream unavailable")
+		}
+		return string(payload), nil
+	})
+
+	pool := NewLimited(2, WithDeadLetter(sink.Handle))
+
+	for _, id := range []string{"a", "b"} {
+		registry.Queue(pool, Task{Name: "send", Payload: []byte(id)}, WithID(id), WithRetries(1)).Wait()
+	}
+
+	// not replayable, queued as a WorkFunc
+	pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		return nil, errors.New("closure")
+	}).Wait()
+
+	pool.Close()
+	Equal(t, sink.Close(), nil)
+
+	letters, err := ReadDeadLetters(path)
+	Equal(t, err, nil)
+	Equal(t, len(letters), 3)
+
+	dl := letters[0]
+	Equal(t, dl.ID, "a")
+	Equal(t, dl.Task.Name, "send")
+	Equal(t, string(dl.Task.Payload), "a")
+	Equal(t, len(dl.Attempts), 2)
+	Equal(t, dl.Attempts[1].Error, "downstream unavailable")
+	Equal(t, dl.Queued.IsZero(), false)
+	Equal(t, dl.Attempts[0].Finished.Sub(dl.Queued) < time.Second, true)
+
+	// replayed once the downstream has recovered
+	m.Lock()
+	healthy = true
+	m.Unlock()
+
+	pool.Reset()
+	defer pool.Close()
+
+	units, err := ReplayDeadLetters(path, registry, pool)
+	Equal(t, err, nil)
+	Equal(t, len(units), 2)
+
+	for i, wu := range units {
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+		Equal(t, wu.Value(), []string{"a", "b"}[i])
+	}
+
+	// malformed files are rejected
+	Equal(t, os.WriteFile(path, []byte("{\"id\":\"a\"}\nnot json\n"), 0644), nil)
+
+	_, err = ReplayDeadLetters(path, registry, pool)
+	NotEqual(t, err, nil)
+	MatchRegex(t, err.Error(), "line 2")
+}
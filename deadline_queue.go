@@ -0,0 +1,151 @@
+package pool
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// deadlineEntry is a pending Work Unit along with the deadline it's ordered by
+type deadlineEntry struct {
+	wu       *workUnit
+	deadline time.Time
+	seq      uint64 // order queued, breaks ties
+}
+
+// deadlineHeap is a min heap of pending Work Units, earliest deadline first and
+// those without a deadline last, in the order they were queued.
+type deadlineHeap []deadlineEntry
+
+func (h deadlineHeap) Len() int { return len(h) }
+
+func (h deadlineHeap) Less(i, j int) bool {
+
+	a, b := h[i], h[j]
+
+	if a.deadline.IsZero() != b.deadline.IsZero() {
+		return b.deadline.IsZero()
+	}
+
+	if !a.deadline.Equal(b.deadline) {
+		return a.deadline.Before(b.deadline)
+	}
+
+	return a.seq < b.seq
+}
+
+func (h deadlineHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *deadlineHeap) Push(x interface{}) {
+	*h = append(*h, x.(deadlineEntry))
+}
+
+func (h *deadlineHeap) Pop() interface{} {
+
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = deadlineEntry{} // release the reference
+	*h = old[:n-1]
+
+	return e
+}
+
+// deadlineQueue holds the pending Work Units of one generation of a limited pool's
+// workers when scheduling earliest deadline first, see WithEarliestDeadlineFirst().
+// Unlike the steal queues there is a single queue, guarded by pool.m, as every
+// worker must agree on which unit is the most urgent.
+type deadlineQueue struct {
+	pool   *limitedPool
+	units  deadlineHeap
+	seq    uint64
+	closed bool
+}
+
+func newDeadlineQueue(p *limitedPool) *deadlineQueue {
+	return &deadlineQueue{pool: p}
+}
+
+// push adds the Work Unit ordered by it's deadline, returning false if the queue has been closed.
+func (dq *deadlineQueue) push(wu *workUnit) bool {
+
+	deadline := wu.deadline
+
+	if deadline.IsZero() && wu.ctx != nil {
+		deadline, _ = wu.ctx.Deadline()
+	}
+
+	p := dq.pool
+	p.m.Lock()
+
+	if dq.closed {
+		p.m.Unlock()
+		return false
+	}
+
+	dq.seq++
+	heap.Push(&dq.units, deadlineEntry{wu: wu, deadline: deadline, seq: dq.seq})
+
+	p.cond.Signal()
+	p.m.Unlock()
+
+	return true
+}
+
+// take returns the pending Work Unit with the earliest deadline, cancelling those whose
+// deadline has passed along the way. It blocks while there is no work and returns nil
+// once the queue has been closed.
+func (dq *deadlineQueue) take(i int) *workUnit {
+
+	p := dq.pool
+
+	for {
+		p.m.Lock()
+
+		for len(dq.units) == 0 && !dq.closed {
+			p.cond.Wait()
+		}
+
+		if dq.closed {
+			p.m.Unlock()
+			return nil
+		}
+
+		e := heap.Pop(&dq.units).(deadlineEntry)
+
+		// unlocked before discarding the unit as retiring
+		// it may dispatch waiting units back to the queue
+		p.m.Unlock()
+
+		// support for individual WorkUnit cancellation
+		// and batch job cancellation
+		if e.wu.cancelled.Load() != nil {
+			p.retire(e.wu)
+			continue
+		}
+
+		if !e.deadline.IsZero() && !time.Now().Before(e.deadline) {
+			e.wu.cancelWithError(&CancelledError{Cause: &TimeoutError{Deadline: e.deadline, err: context.DeadlineExceeded}})
+			p.retire(e.wu)
+			continue
+		}
+
+		return e.wu
+	}
+}
+
+// close closes the queue calling fn for each Work Unit still pending.
+// NOTE: pool.m must be held
+func (dq *deadlineQueue) close(fn func(wu *workUnit)) {
+
+	dq.closed = true
+
+	for _, e := range dq.units {
+		fn(e.wu)
+	}
+
+	dq.units = nil
+
+	// wake all idle workers so they exit
+	dq.pool.cond.Broadcast()
+}
@@ -38,7 +38,8 @@ important usage information
       cancel as soon as if called from outside.
 
     - When Batching DO NOT FORGET TO CALL batch.QueueComplete(),
-      if you do the Batch WILL deadlock
+      if you do the Batch WILL deadlock; batch.QueueFrom(ch) calls
+      it for you once ch is closed
 
     - It is your responsibility to call WorkUnit.IsCancelled() to check if it's cancelled
       after a blocking operation like waiting for a connection from a pool. (optional)
@@ -1,37 +1,374 @@
 package pool
 
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
 const (
 	errCancelled = "ERROR: Work Unit Cancelled"
 	errRecovery  = "ERROR: Work Unit failed due to a recoverable error: '%v'\n, Stack Trace:\n %s"
 	errClosed    = "ERROR: Work Unit added/run after the pool had been closed or cancelled"
+
+	errTaskNotRegistered = "ERROR: Task '%s' has not been registered"
+	errBatchCancelled    = "ERROR: Batch Cancelled"
+	errTimeout           = "ERROR: Work Unit timed out"
+	errUnit              = "Work Unit #%d failed: %s"
+	errUnitID            = "Work Unit #%d '%s' failed: %s"
+	errCircuitOpen       = "ERROR: Circuit '%s' is open"
+	errOverloaded        = "ERROR: Work Unit shed, waited %s to run exceeding the maximum of %s"
+	errExpired           = "ERROR: Work Unit expired, not started within it's TTL of %s"
+	errWorkerInit        = "ERROR: Work Unit failed, it's worker could not be initialized: %s"
 )
 
-// ErrRecovery contains the error when a consumer goroutine needed to be recovers
-type ErrRecovery struct {
-	s string
+// Sentinel errors for use with errors.Is, every error returned by the pool matches
+// the sentinel of it's kind eg. errors.Is(wu.Error(), pool.ErrCancelled)
+var (
+	// ErrCancelled matches any CancelledError
+	ErrCancelled = errors.New(errCancelled)
+
+	// ErrPoolClosed matches any PoolClosedError
+	ErrPoolClosed = errors.New(errClosed)
+
+	// ErrRecovery matches any RecoveryError
+	ErrRecovery = errors.New("ERROR: Work Unit failed due to a recoverable error")
+
+	// ErrTaskNotRegistered matches any TaskNotRegisteredError
+	ErrTaskNotRegistered = errors.New("ERROR: Task has not been registered")
+
+	// ErrCircuitOpen matches any CircuitOpenError
+	ErrCircuitOpen = errors.New("ERROR: Circuit is open")
+
+	// ErrOverloaded matches any OverloadError
+	ErrOverloaded = errors.New("ERROR: Work Unit shed, pool overloaded")
+
+	// ErrExpired matches any ExpiredError
+	ErrExpired = errors.New("ERROR: Work Unit expired")
+
+	// ErrWorkerInit matches any WorkerInitError
+	ErrWorkerInit = errors.New("ERROR: Worker could not be initialized")
+)
+
+// RecoveryError contains the error when a consumer goroutine needed to be recovers
+type RecoveryError struct {
+
+	// Value is the value that was passed to panic
+	Value interface{}
+
+	// Stack is the call stack of the goroutine that panicked, starting at the
+	// frame that called panic
+	Stack []Frame
+
+	// UnitID is the ID of the Work Unit that panicked, if any, see WithID()
+	UnitID string
+}
+
+// Frame is a single function call of a recovered panic's stack
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// String prints the Frame in the same format as a runtime stack trace
+func (f Frame) String() string {
+	return fmt.Sprintf("%s\n\t%s:%d", f.Function, f.File, f.Line)
+}
+
+// newRecoveryError returns the recovery error for the Work Unit, by it's ID, that panicked with the value v.
+// NOTE: must be called from the deferred function that recovered the panic.
+func newRecoveryError(id string, v interface{}) *RecoveryError {
+
+	pcs := make([]uintptr, 64)
+
+	// grown until the whole stack fits, however deep the panic
+	for {
+		n := runtime.Callers(1, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+
+	var stack []Frame
+	var panicked bool
+
+	frames := runtime.CallersFrames(pcs)
+
+	for {
+		frame, more := frames.Next()
+
+		// skip the recovery frames, everything up to and including the call to panic
+		if panicked {
+			stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		} else if frame.Function == "runtime.gopanic" {
+			panicked = true
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return &RecoveryError{
+		Value:  v,
+		Stack:  stack,
+		UnitID: id,
+	}
 }
 
 // Error prints recovery error
-func (e *ErrRecovery) Error() string {
-	return e.s
+func (e *RecoveryError) Error() string {
+
+	trace := make([]string, len(e.Stack))
+
+	for i, f := range e.Stack {
+		trace[i] = f.String()
+	}
+
+	return fmt.Sprintf(errRecovery, e.Value, strings.Join(trace, "\n"))
+}
+
+// Is reports whether target is the ErrRecovery sentinel
+func (e *RecoveryError) Is(target error) bool {
+	return target == ErrRecovery
 }
 
-// ErrPoolClosed is the error returned to all work units that may have been in or added to the pool after it's closing.
-type ErrPoolClosed struct {
-	s string
+// Unwrap returns the panic value if it was an error
+func (e *RecoveryError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
 }
 
+// PoolClosedError is the error returned to all work units that may have been in or added to the pool after it's closing.
+type PoolClosedError struct{}
+
 // Error prints Work Unit Close error
-func (e *ErrPoolClosed) Error() string {
-	return e.s
+func (e *PoolClosedError) Error() string {
+	return errClosed
 }
 
-// ErrCancelled is the error returned to a Work Unit when it has been cancelled.
-type ErrCancelled struct {
-	s string
+// Is reports whether target is the ErrPoolClosed sentinel
+func (e *PoolClosedError) Is(target error) bool {
+	return target == ErrPoolClosed
+}
+
+// CancelledError is the error returned to a Work Unit when it has been cancelled.
+type CancelledError struct {
+
+	// Cause is the reason the Work Unit was cancelled eg. a *PoolClosedError,
+	// *BatchCancelledError, *TimeoutError or context error. It is nil when the
+	// Work Unit or Pool was cancelled directly.
+	Cause error
 }
 
 // Error prints Work Unit Cancellation error
-func (e *ErrCancelled) Error() string {
-	return e.s
+func (e *CancelledError) Error() string {
+
+	if e.Cause == nil {
+		return errCancelled
+	}
+
+	return errCancelled + ": " + e.Cause.Error()
+}
+
+// Is reports whether target is the ErrCancelled sentinel
+func (e *CancelledError) Is(target error) bool {
+	return target == ErrCancelled
+}
+
+// Unwrap returns the cause of the cancellation
+func (e *CancelledError) Unwrap() error {
+	return e.Cause
+}
+
+// BatchCancelledError is the cause of a Work Unit's cancellation when it's Batch was cancelled.
+type BatchCancelledError struct{}
+
+// Error prints Batch Cancellation error
+func (e *BatchCancelledError) Error() string {
+	return errBatchCancelled
+}
+
+// TimeoutError is the cause of a Work Unit's cancellation when it's deadline passed.
+type TimeoutError struct {
+
+	// Deadline is the time the Work Unit had to complete by
+	Deadline time.Time
+
+	err error
+}
+
+// Error prints Work Unit Timeout error
+func (e *TimeoutError) Error() string {
+	return errTimeout
+}
+
+// Timeout always reports true, it allows TimeoutError to be
+// checked the same as net.Error and friends.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Unwrap returns the underlying error, if any, eg. context.DeadlineExceeded
+func (e *TimeoutError) Unwrap() error {
+	return e.err
+}
+
+// TaskNotRegisteredError is the error returned to a Work Unit when the Task it was
+// queued for has no registered TaskHandler.
+type TaskNotRegisteredError struct {
+	Name string
+}
+
+// Error prints Task not registered error
+func (e *TaskNotRegisteredError) Error() string {
+	return fmt.Sprintf(errTaskNotRegistered, e.Name)
+}
+
+// Is reports whether target is the ErrTaskNotRegistered sentinel
+func (e *TaskNotRegisteredError) Is(target error) bool {
+	return target == ErrTaskNotRegistered
+}
+
+// CircuitOpenError is the error returned to a Work Unit failed without running because
+// the circuit breaker it was queued under is open, see WithCircuitBreaker().
+type CircuitOpenError struct {
+
+	// Name is the name of the circuit breaker, see WithBreaker()
+	Name string
+
+	// Until is when the breaker half-opens to let a trial Work Unit through
+	Until time.Time
+}
+
+// Error prints Circuit open error
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf(errCircuitOpen, e.Name)
+}
+
+// Is reports whether target is the ErrCircuitOpen sentinel
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// OverloadError is the error returned to a Work Unit shed by the pool because it waited,
+// or was expected to wait, too long to run, see WithLoadShedding().
+type OverloadError struct {
+
+	// Waited is how long the Work Unit waited before being dropped or, when rejected
+	// as it was queued, how long it was expected to wait to run
+	Waited time.Duration
+
+	// MaxWait is the longest a Work Unit may wait to run
+	MaxWait time.Duration
+}
+
+// Error prints Overload error
+func (e *OverloadError) Error() string {
+	return fmt.Sprintf(errOverloaded, e.Waited, e.MaxWait)
+}
+
+// Is reports whether target is the ErrOverloaded sentinel
+func (e *OverloadError) Is(target error) bool {
+	return target == ErrOverloaded
+}
+
+// ExpiredError is the error returned to a Work Unit that was not started within it's
+// time-to-live, it never runs, see WithTTL().
+type ExpiredError struct {
+
+	// TTL is the Work Unit's time-to-live
+	TTL time.Duration
+}
+
+// Error prints Expired error
+func (e *ExpiredError) Error() string {
+	return fmt.Sprintf(errExpired, e.TTL)
+}
+
+// Is reports whether target is the ErrExpired sentinel
+func (e *ExpiredError) Is(target error) bool {
+	return target == ErrExpired
+}
+
+// WorkerInitError is the error returned to a Work Unit, without running it, when the worker
+// that took it could not initialize it's state, see WithWorkerInit().
+type WorkerInitError struct {
+
+	// Err is the error returned by the WorkerInit function, or a RecoveryError if it panicked
+	Err error
+}
+
+// Error prints Worker init error
+func (e *WorkerInitError) Error() string {
+	return fmt.Sprintf(errWorkerInit, e.Err)
+}
+
+// Is reports whether target is the ErrWorkerInit sentinel
+func (e *WorkerInitError) Is(target error) bool {
+	return target == ErrWorkerInit
+}
+
+// Unwrap returns the error initializing the worker
+func (e *WorkerInitError) Unwrap() error {
+	return e.Err
+}
+
+// UnitError identifies the Work Unit of a Batch that failed along with it's error.
+type UnitError struct {
+
+	// Index is the position the Work Unit was Queued in the Batch, starting at 0
+	Index int
+
+	// ID is the ID of the Work Unit, if any, see WithID()
+	ID string
+
+	// Err is the Work Unit's error
+	Err error
+}
+
+// Error prints the Work Unit's identity and error
+func (e *UnitError) Error() string {
+
+	if len(e.ID) == 0 {
+		return fmt.Sprintf(errUnit, e.Index, e.Err)
+	}
+
+	return fmt.Sprintf(errUnitID, e.Index, e.ID, e.Err)
+}
+
+// Unwrap returns the Work Unit's error
+func (e *UnitError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is the combined error of all the failed Work Units of a Batch.
+// Like the error returned by errors.Join it unwraps to all of it's errors
+// so errors.Is and errors.As match any of them.
+type BatchError struct {
+
+	// Errors contains a *UnitError for each failed Work Unit
+	Errors []error
+}
+
+// Error prints the error of each failed Work Unit, one per line
+func (e *BatchError) Error() string {
+
+	s := make([]string, len(e.Errors))
+
+	for i, err := range e.Errors {
+		s[i] = err.Error()
+	}
+
+	return strings.Join(s, "\n")
+}
+
+// Unwrap returns the errors of all failed Work Units
+func (e *BatchError) Unwrap() []error {
+	return e.Errors
 }
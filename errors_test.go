@@ -0,0 +1,170 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestErrorsIsAs(t *testing.T) {
+
+	block := make(chan struct{})
+
+	blocking := func(WorkUnit) (interface{}, error) {
+		<-block
+		return nil, nil
+	}
+
+	noop := func(WorkUnit) (interface{}, error) {
+		return nil, nil
+	}
+
+	pool := NewLimited(1)
+	defer pool.Close()
+
+	pool.Queue(blocking)
+	time.Sleep(time.Millisecond * 50) // let the worker pick up the blocking unit
+
+	// direct cancellation, no cause
+	wu := pool.Queue(noop)
+	wu.Cancel()
+	wu.Wait()
+
+	var cancelled *CancelledError
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, errors.As(wu.Error(), &cancelled), true)
+	Equal(t, cancelled.Cause, nil)
+	Equal(t, wu.Error().Error(), "ERROR: Work Unit Cancelled")
+
+	// batch cancellation
+	batch := pool.Batch()
+	batch.Queue(noop)
+	batch.Cancel()
+
+	for wu := range batch.Results() {
+		var bc *BatchCancelledError
+		Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+		Equal(t, errors.As(wu.Error(), &bc), true)
+	}
+
+	// context cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	wu = pool.Queue(noop, WithContext(ctx))
+	cancel()
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, errors.Is(wu.Error(), context.Canceled), true)
+
+	// context deadline
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	wu = pool.Queue(noop, WithContext(ctx))
+	wu.Wait()
+
+	var timeout *TimeoutError
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, errors.As(wu.Error(), &timeout), true)
+	Equal(t, timeout.Timeout(), true)
+	Equal(t, errors.Is(wu.Error(), context.DeadlineExceeded), true)
+
+	close(block)
+
+	// pool closed with units pending
+	release := make(chan struct{})
+
+	pool = NewLimited(1)
+	pool.Queue(func(WorkUnit) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	time.Sleep(time.Millisecond * 50) // let the worker pick up the blocking unit
+
+	wu = pool.Queue(noop)
+	time.Sleep(time.Millisecond * 50) // let the unit make it into the pool
+	pool.Close()
+	close(release)
+	wu.Wait()
+
+	var closed *PoolClosedError
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, errors.Is(wu.Error(), ErrPoolClosed), true)
+	Equal(t, errors.As(wu.Error(), &closed), true)
+
+	// queued after close
+	wu = pool.Queue(noop)
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), false)
+	Equal(t, errors.Is(wu.Error(), ErrPoolClosed), true)
+}
+
+func TestErrorsRecovery(t *testing.T) {
+
+	cause := errors.New("root cause")
+
+	pool := New()
+	defer pool.Close()
+
+	wu := pool.Queue(func(WorkUnit) (interface{}, error) {
+		panic(cause)
+	})
+	wu.Wait()
+
+	var rec *RecoveryError
+	Equal(t, errors.Is(wu.Error(), ErrRecovery), true)
+	Equal(t, errors.As(wu.Error(), &rec), true)
+	Equal(t, errors.Is(wu.Error(), cause), true)
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), false)
+}
+
+func TestErrorsRecoveryDeepStack(t *testing.T) {
+
+	pool := NewLimited(1)
+	defer pool.Close()
+
+	var recurse func(n int)
+	recurse = func(n int) {
+		if n == 0 {
+			panic("deep")
+		}
+		recurse(n - 1)
+	}
+
+	wu := pool.Queue(func(WorkUnit) (interface{}, error) {
+		recurse(200)
+		return nil, nil
+	})
+	wu.Wait()
+
+	var rec *RecoveryError
+	Equal(t, errors.As(wu.Error(), &rec), true)
+	Equal(t, len(rec.Stack) > 200, true)
+
+	// the whole stack is recorded, down to the WorkFunc and the worker running it
+	var workFunc, worker bool
+
+	for _, f := range rec.Stack {
+		workFunc = workFunc || strings.Contains(f.Function, ".TestErrorsRecoveryDeepStack.func2")
+		worker = worker || strings.Contains(f.Function, "newWorker")
+	}
+
+	Equal(t, workFunc, true)
+	Equal(t, worker, true)
+}
@@ -0,0 +1,202 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+var _ WorkUnit = new(hedgedUnit)
+
+// hedgedUnit is the Work Unit returned by QueueHedged, standing for all copies of the
+// work queued. It's done once a copy succeeds, every copy has failed or it's cancelled.
+type hedgedUnit struct {
+	pool      Pool
+	fn        WorkFunc
+	opts      []UnitOption
+	id        string
+	after     time.Duration
+	maxCopies uint
+	copies    []WorkUnit
+	pending   uint // copies queued that haven't finished
+	timer     *time.Timer
+	winner    WorkUnit
+	value     interface{}
+	err       error
+	cancelled bool
+	done      chan struct{}
+	m         sync.Mutex
+}
+
+// queueHedged queues fn on the pool, queueing another copy each time after passes
+// without one having succeeded, up to maxCopies, see Pool.QueueHedged()
+func queueHedged(p Pool, fn WorkFunc, after time.Duration, maxCopies uint, opts []UnitOption) WorkUnit {
+
+	h := &hedgedUnit{
+		pool:      p,
+		fn:        fn,
+		opts:      opts,
+		id:        unitID(opts),
+		after:     after,
+		maxCopies: max(maxCopies, 1),
+		done:      make(chan struct{}),
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.hedge()
+
+	// queue every copy straight away, nothing to wait for
+	for after <= 0 && uint(len(h.copies)) < h.maxCopies {
+		h.hedge()
+	}
+
+	if after > 0 && h.maxCopies > 1 {
+		h.timer = time.AfterFunc(after, h.tick)
+	}
+
+	return h
+}
+
+// isDone reports whether the hedged unit has finished
+// NOTE: h.m must be held
+func (h *hedgedUnit) isDone() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// hedge queues another copy of the work and waits on it in it's own goroutine
+// NOTE: h.m must be held
+func (h *hedgedUnit) hedge() {
+
+	wu := h.pool.Queue(h.fn, h.opts...)
+
+	h.copies = append(h.copies, wu)
+	h.pending++
+
+	go func() {
+		wu.Wait()
+		h.finished(wu)
+	}()
+}
+
+// tick queues another copy once after has passed without a copy succeeding
+func (h *hedgedUnit) tick() {
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if h.isDone() || uint(len(h.copies)) >= h.maxCopies {
+		return
+	}
+
+	h.hedge()
+
+	if uint(len(h.copies)) < h.maxCopies {
+		h.timer.Reset(h.after)
+	}
+}
+
+// finished records the outcome of a copy, the first to succeed wins and cancels the others.
+// If every copy fails the hedged unit fails with the error of the first.
+func (h *hedgedUnit) finished(wu WorkUnit) {
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.pending--
+
+	if h.isDone() {
+		return
+	}
+
+	if wu.Error() == nil {
+		h.winner = wu
+		h.finish(wu.Value(), nil)
+		return
+	}
+
+	if h.err == nil {
+		h.err = wu.Error()
+	}
+
+	if h.pending == 0 && uint(len(h.copies)) >= h.maxCopies {
+		h.finish(nil, h.err)
+	}
+}
+
+// finish cancels any copies still pending and completes the hedged unit
+// NOTE: h.m must be held
+func (h *hedgedUnit) finish(value interface{}, err error) {
+
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+
+	for _, wu := range h.copies {
+		if wu != h.winner {
+			wu.Cancel()
+		}
+	}
+
+	h.value, h.err = value, err
+	close(h.done)
+}
+
+// Wait blocks until a copy has succeeded, all have failed or the hedged unit is cancelled
+func (h *hedgedUnit) Wait() {
+	<-h.done
+}
+
+// Value returns the value of the copy that succeeded first
+func (h *hedgedUnit) Value() interface{} {
+	return h.value
+}
+
+// Error returns the error of the first copy to fail if none succeeded
+func (h *hedgedUnit) Error() error {
+	return h.err
+}
+
+// Cancel cancels every copy of the work not yet finished
+func (h *hedgedUnit) Cancel() {
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if h.isDone() {
+		return
+	}
+
+	h.cancelled = true
+	h.finish(nil, &CancelledError{})
+}
+
+// IsCancelled returns if the hedged unit has been cancelled
+func (h *hedgedUnit) IsCancelled() bool {
+	h.m.Lock()
+	defer h.m.Unlock()
+	return h.cancelled
+}
+
+// ID returns the caller provided identifier shared by every copy, if any
+func (h *hedgedUnit) ID() string {
+	return h.id
+}
+
+// WorkerState returns the worker state of the copy that succeeded, if any
+func (h *hedgedUnit) WorkerState() interface{} {
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if h.winner == nil {
+		return nil
+	}
+
+	return h.winner.WorkerState()
+}
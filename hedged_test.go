@@ -0,0 +1,177 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestQueueHedged(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(4), New()} {
+
+		var started int32
+		var m sync.Mutex
+		var copies []WorkUnit
+
+		stalled := make(chan struct{})
+
+		// the first copy stalls, the others are quick
+		read := func(wu WorkUnit) (interface{}, error) {
+
+			m.Lock()
+			copies = append(copies, wu)
+			m.Unlock()
+
+			if atomic.AddInt32(&started, 1) == 1 {
+				<-stalled
+				return "stalled", nil
+			}
+
+			return "hedged", nil
+		}
+
+		start := time.Now()
+
+		wu := pool.QueueHedged(read, time.Millisecond*20, 3, WithID("read"))
+		wu.Wait()
+
+		Equal(t, wu.Error(), nil)
+		Equal(t, wu.Value(), "hedged")
+		Equal(t, wu.ID(), "read")
+		Equal(t, wu.IsCancelled(), false)
+		Equal(t, time.Since(start) < time.Millisecond*100, true)
+
+		// the losing copy is cancelled and no more are queued
+		m.Lock()
+		Equal(t, copies[0].IsCancelled(), true)
+		m.Unlock()
+
+		time.Sleep(time.Millisecond * 50)
+		Equal(t, atomic.LoadInt32(&started), int32(2))
+
+		close(stalled)
+		copies[0].Wait()
+		Equal(t, errors.Is(copies[0].Error(), ErrCancelled), true)
+
+		// not hedged when the first copy is quick enough
+		atomic.StoreInt32(&started, 1)
+
+		wu = pool.QueueHedged(read, time.Millisecond*20, 3)
+		wu.Wait()
+		Equal(t, wu.Value(), "hedged")
+
+		time.Sleep(time.Millisecond * 50)
+		Equal(t, atomic.LoadInt32(&started), int32(2))
+
+		pool.Close()
+	}
+}
+
+func TestQueueHedgedFailures(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(4), New()} {
+
+		var started int32
+
+		fail := func(wu WorkUnit) (interface{}, error) {
+			return nil, errors.New([]string{"first", "second", "third"}[atomic.AddInt32(&started, 1)-1])
+		}
+
+		// fails with the first error once every copy has
+		wu := pool.QueueHedged(fail, time.Millisecond*5, 3)
+		wu.Wait()
+
+		Equal(t, wu.Error().Error(), "first")
+		Equal(t, atomic.LoadInt32(&started), int32(3))
+
+		// every copy queued straight away
+		atomic.StoreInt32(&started, 0)
+
+		wu = pool.QueueHedged(fail, 0, 2)
+		wu.Wait()
+
+		NotEqual(t, wu.Error(), nil)
+		Equal(t, atomic.LoadInt32(&started), int32(2))
+
+		// cancelling cancels every copy
+		release := make(chan struct{})
+		var copies []WorkUnit
+		var m sync.Mutex
+
+		wu = pool.QueueHedged(func(wu WorkUnit) (interface{}, error) {
+			m.Lock()
+			copies = append(copies, wu)
+			m.Unlock()
+			<-release
+			return nil, nil
+		}, time.Millisecond*5, 2)
+
+		time.Sleep(time.Millisecond * 30)
+
+		wu.Cancel()
+		wu.Wait()
+
+		Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+		Equal(t, wu.IsCancelled(), true)
+
+		m.Lock()
+		Equal(t, len(copies), 2)
+		for _, c := range copies {
+			Equal(t, c.IsCancelled(), true)
+		}
+		m.Unlock()
+
+		close(release)
+		pool.Close()
+	}
+}
+
+func TestQueueHedgedLoserPanics(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(4), New()} {
+
+		var started int32
+		panicked := make(chan struct{})
+
+		wu := pool.QueueHedged(func(wu WorkUnit) (interface{}, error) {
+			if atomic.AddInt32(&started, 1) == 1 {
+				defer close(panicked)
+				time.Sleep(time.Millisecond * 30)
+				panic("loser")
+			}
+			return "winner", nil
+		}, 0, 2)
+		wu.Wait()
+
+		Equal(t, wu.Error(), nil)
+		Equal(t, wu.Value(), "winner")
+
+		<-panicked
+
+		// the pool carries on once the cancelled copy has panicked
+		wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+			return 1, nil
+		})
+		wu.Wait()
+
+		Equal(t, wu.Value(), 1)
+
+		pool.Close()
+	}
+}
@@ -1,25 +1,52 @@
 package pool
 
 import (
-	"fmt"
-	"math"
-	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var _ Pool = new(limitedPool)
 
+// initBackoff is how long a limited pool worker first waits to retry initializing it's
+// state after WorkerInit failed, doubling with each failure up to maxInitBackoff.
+const (
+	initBackoff    = time.Millisecond * 10
+	maxInitBackoff = time.Second
+)
+
+// unitScheduler holds the pending Work Units of one generation of a limited pool's
+// workers, replaced each time the pool is Reset() after a Close() or Cancel().
+type unitScheduler interface {
+
+	// push adds the Work Unit, returning false if the scheduler has been closed
+	push(wu *workUnit) bool
+
+	// take returns the next Work Unit for the worker at index i, blocking while
+	// there is no work and returning nil once the scheduler has been closed.
+	take(i int) *workUnit
+
+	// close closes the scheduler calling fn for each Work Unit still pending.
+	// NOTE: pool.m must be held
+	close(fn func(wu *workUnit))
+}
+
 // limitedPool contains all information for a limited pool instance.
 type limitedPool struct {
 	workers uint
-	work    chan *workUnit
-	cancel  chan struct{}
+	opts    options
+	sched   atomic.Value       // unitScheduler
+	gates   []gate             // see admission.go
+	admit   func(wu *workUnit) // passes a Work Unit through the gates to dispatch()
+	cond    *sync.Cond
 	closed  bool
-	m       sync.RWMutex
+	m       sync.Mutex
 }
 
-// NewLimited returns a new limited pool instance
-func NewLimited(workers uint) Pool {
+// NewLimited returns a new limited pool instance. Each worker has it's own
+// queue of pending work and steals from the others when it runs out, unless
+// scheduling earliest deadline first, see WithEarliestDeadlineFirst().
+func NewLimited(workers uint, opts ...Option) Pool {
 
 	if workers == 0 {
 		panic("invalid workers '0'")
@@ -27,8 +54,12 @@ func NewLimited(workers uint) Pool {
 
 	p := &limitedPool{
 		workers: workers,
+		opts:    newOptions(opts),
 	}
 
+	p.gates, p.admit = newGates(&p.opts, p.dispatch, p.retire)
+
+	p.cond = sync.NewCond(&p.m)
 	p.initialize()
 
 	return p
@@ -36,37 +67,66 @@ func NewLimited(workers uint) Pool {
 
 func (p *limitedPool) initialize() {
 
-	p.work = make(chan *workUnit, p.workers*2)
-	p.cancel = make(chan struct{})
+	var s unitScheduler
+
+	if p.opts.edf {
+		s = newDeadlineQueue(p)
+	} else {
+		s = newStealQueues(p)
+	}
+
+	p.sched.Store(s)
 	p.closed = false
 
 	// fire up workers here
 	for i := 0; i < int(p.workers); i++ {
-		p.newWorker(p.work, p.cancel)
+		p.newWorker(s, i)
 	}
 }
 
-// passing work and cancel channels to newWorker() to avoid any potential race condition
-// betweeen p.work read & write
-func (p *limitedPool) newWorker(work chan *workUnit, cancel chan struct{}) {
+// passing the scheduler to newWorker() so that workers from before
+// a Close() or Cancel() exit rather than serving the pool after a Reset()
+func (p *limitedPool) newWorker(s unitScheduler, i int) {
 	go func(p *limitedPool) {
 
 		var wu *workUnit
+		var state interface{}
 
-		defer func(p *limitedPool) {
-			if err := recover(); err != nil {
+		state, initErr := p.initWorker()
+		backoff := initBackoff
+		retry := time.Now().Add(backoff)
 
-				trace := make([]byte, 1<<16)
-				n := runtime.Stack(trace, true)
+		defer func(p *limitedPool) {
 
-				s := fmt.Sprintf(errRecovery, err, string(trace[:int(math.Min(float64(n), float64(7000)))]))
+			r := recover()
 
+			if r != nil {
 				iwu := wu
-				iwu.err = &ErrRecovery{s: s}
-				close(iwu.done)
 
+				// committed so it can't be cancelled from here on, but may have
+				// been while running, eg. by it's context, and already be done
+				iwu.writing.Store(struct{}{})
+
+				if iwu.finish() {
+					iwu.err = p.opts.recovered(iwu, r)
+					p.opts.deadLetter(iwu)
+					close(iwu.done)
+					iwu.release()
+				}
+
+				p.retire(iwu)
+			}
+
+			// the worker is exiting, whether cancelled or recovered, the state
+			// may have been left in an unknown state by a panic so is not reused,
+			// there's none to tear down if it was never initialized
+			if p.opts.workerTeardown != nil && initErr == nil {
+				p.opts.workerTeardown(state)
+			}
+
+			if r != nil {
 				// need to fire up new worker to replace this one as this one is exiting
-				p.newWorker(p.work, p.cancel)
+				p.newWorker(s, i)
 			}
 		}(p)
 
@@ -74,69 +134,132 @@ func (p *limitedPool) newWorker(work chan *workUnit, cancel chan struct{}) {
 		var err error
 
 		for {
-			select {
-			case wu = <-work:
+			if wu = s.take(i); wu == nil {
+				return
+			}
+
+			// expired while waiting, see WithTTL()
+			if !wu.begin() {
+				p.retire(wu)
+				continue
+			}
 
-				// possible for one more nilled out value to make it
-				// through when channel closed, don't quite understad the why
-				if wu == nil {
-					continue
+			// retried with backoff rather than replacing the worker, which would just fail again
+			if initErr != nil && !time.Now().Before(retry) {
+				if state, initErr = p.initWorker(); initErr != nil {
+					backoff = min(backoff*2, maxInitBackoff)
+					retry = time.Now().Add(backoff)
 				}
+			}
 
-				// support for individual WorkUnit cancellation
-				// and batch job cancellation
-				if wu.cancelled.Load() == nil {
-					value, err = wu.fn(wu)
+			if initErr != nil {
+				p.initFailed(wu, initErr)
+				continue
+			}
 
-					wu.writing.Store(struct{}{})
+			wu.state = state
+			wu.started = time.Now()
 
-					// need to check again in case the WorkFunc cancelled this unit of work
-					// otherwise we'll have a race condition
-					if wu.cancelled.Load() == nil && wu.cancelling.Load() == nil {
-						wu.value, wu.err = value, err
+			if p.opts.shed(wu) {
+				p.retire(wu)
+				continue
+			}
 
-						// who knows where the Done channel is being listened to on the other end
-						// don't want this to block just because caller is waiting on another unit
-						// of work to be done first so we use close
-						close(wu.done)
-					}
-				}
+			value, err = wu.run()
 
-			case <-cancel:
-				return
+			wu.writing.Store(struct{}{})
+			wu.release()
+
+			// may have been cancelled while running, including by
+			// the WorkFunc itself, in which case it's already done
+			if wu.finish() {
+				wu.value, wu.err = value, err
+				p.opts.deadLetter(wu)
+
+				// who knows where the Done channel is being listened to on the other end
+				// don't want this to block just because caller is waiting on another unit
+				// of work to be done first so we use close
+				close(wu.done)
 			}
+
+			p.retire(wu)
 		}
 
 	}(p)
 }
 
+// initWorker runs the WorkerInit function, if any, returning the worker's state or
+// the error it returned, a RecoveryError if it panicked, see WithWorkerInit().
+func (p *limitedPool) initWorker() (state interface{}, err error) {
+
+	if p.opts.workerInit == nil {
+		return nil, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = newRecoveryError("", r)
+		}
+	}()
+
+	return p.opts.workerInit()
+}
+
+// initFailed fails the Work Unit taken by a worker without state to run it with
+func (p *limitedPool) initFailed(wu *workUnit, err error) {
+
+	wu.writing.Store(struct{}{})
+	wu.release()
+
+	// may have been cancelled just before being committed
+	if wu.finish() {
+		wu.err = &WorkerInitError{Err: err}
+		close(wu.done)
+	}
+
+	p.retire(wu)
+}
+
 // Queue queues the work to be run, and starts processing immediately
-func (p *limitedPool) Queue(fn WorkFunc) WorkUnit {
+func (p *limitedPool) Queue(fn WorkFunc, opts ...UnitOption) WorkUnit {
 
 	w := &workUnit{
-		done: make(chan struct{}),
-		fn:   fn,
+		done:   make(chan struct{}),
+		fn:     fn,
+		weight: 1,
+		queued: time.Now(),
 	}
 
-	go func() {
-		p.m.RLock()
-		if p.closed {
-			w.err = &ErrPoolClosed{s: errClosed}
-			if w.cancelled.Load() == nil {
-				close(w.done)
-			}
-			p.m.RUnlock()
-			return
-		}
+	for _, opt := range opts {
+		opt(w)
+	}
 
-		p.work <- w
+	w.watchContext()
+	w.watchTTL()
 
-		p.m.RUnlock()
-	}()
+	// held back until every configured gate lets it through, see newGates()
+	p.admit(w)
 
 	return w
 }
 
+// dispatch hands the Work Unit to the workers, neither blocks
+// as the scheduler grows to hold any backlog.
+func (p *limitedPool) dispatch(w *workUnit) {
+	if !p.sched.Load().(unitScheduler).push(w) {
+		w.cancelWithError(&PoolClosedError{})
+		p.retire(w)
+	}
+}
+
+// retire releases anything held by the Work Unit while in the pool, once
+// processed by a worker or discarded without running.
+func (p *limitedPool) retire(w *workUnit) {
+	for _, g := range p.gates {
+		g.release(w)
+	}
+}
+
 // Reset reinitializes a pool that has been closed/cancelled back to a working state.
 // if the pool has not been closed/cancelled, nothing happens as the pool is still in
 // a valid running state
@@ -151,6 +274,11 @@ func (p *limitedPool) Reset() {
 
 	// cancelled the pool, not closed it, pool will be usable after calling initialize().
 	p.initialize()
+
+	for _, g := range p.gates {
+		g.reopen()
+	}
+
 	p.m.Unlock()
 }
 
@@ -159,13 +287,17 @@ func (p *limitedPool) closeWithError(err error) {
 	p.m.Lock()
 
 	if !p.closed {
-		close(p.cancel)
-		close(p.work)
 		p.closed = true
-	}
 
-	for wu := range p.work {
-		wu.cancelWithError(err)
+		// first so no more are admitted
+		for _, g := range p.gates {
+			g.close(err)
+		}
+
+		p.sched.Load().(unitScheduler).close(func(wu *workUnit) {
+			wu.cancelWithError(err)
+			p.retire(wu)
+		})
 	}
 
 	p.m.Unlock()
@@ -176,7 +308,7 @@ func (p *limitedPool) closeWithError(err error) {
 // call Reset() to reinitialize the pool for use.
 func (p *limitedPool) Cancel() {
 
-	err := &ErrCancelled{s: errCancelled}
+	err := &CancelledError{}
 	p.closeWithError(err)
 }
 
@@ -185,10 +317,17 @@ func (p *limitedPool) Cancel() {
 // call Reset() to reinitialize the pool for use.
 func (p *limitedPool) Close() {
 
-	err := &ErrPoolClosed{s: errClosed}
+	err := &CancelledError{Cause: &PoolClosedError{}}
 	p.closeWithError(err)
 }
 
+// QueueHedged queues the work to be run, queueing another copy each time after passes without
+// one having succeeded up to maxCopies in all. The first copy to succeed wins and the others
+// are cancelled.
+func (p *limitedPool) QueueHedged(fn WorkFunc, after time.Duration, maxCopies uint, opts ...UnitOption) WorkUnit {
+	return queueHedged(p, fn, after, maxCopies, opts)
+}
+
 // Batch creates a new Batch object for queueing Work Units separate from any others
 // that may be running on the pool. Grouping these Work Units together allows for individual
 // Cancellation of the Batch Work Units without affecting anything else running on the pool
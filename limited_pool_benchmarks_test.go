@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -183,3 +184,40 @@ func BenchmarkLimitedBatchSmallRun(b *testing.B) {
 		b.Fatal("Count Incorrect")
 	}
 }
+
+func BenchmarkLimitedFanOut(b *testing.B) {
+
+	b.ReportAllocs()
+
+	pool := NewLimited(4)
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+
+	// each unit fans out into 4 children, 3 levels deep, all queued from within
+	// the running parent onto it's worker's local queue
+	var fanOut func(depth int) WorkFunc
+
+	fanOut = func(depth int) WorkFunc {
+		return func(wu WorkUnit) (interface{}, error) {
+
+			if depth > 0 {
+				for i := 0; i < 4; i++ {
+					wg.Add(1)
+					pool.Queue(fanOut(depth-1), WithParent(wu))
+				}
+			}
+
+			wg.Done()
+			return nil, nil
+		}
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		wg.Add(1)
+		pool.Queue(fanOut(3))
+		wg.Wait()
+	}
+}
@@ -1,7 +1,12 @@
 package pool
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -97,9 +102,11 @@ func TestCancel(t *testing.T) {
 		wu.Wait()
 
 		if wu.Error() != nil {
-			_, ok := wu.Error().(*ErrCancelled)
+			var cancelled *CancelledError
+			ok := errors.As(wu.Error(), &cancelled)
 			if !ok {
-				_, ok = wu.Error().(*ErrPoolClosed)
+				var closed *PoolClosedError
+				ok = errors.As(wu.Error(), &closed)
 				if ok {
 					Equal(t, wu.Error().Error(), "ERROR: Work Unit added/run after the pool had been closed or cancelled")
 				}
@@ -172,6 +179,523 @@ func TestPanicRecovery(t *testing.T) {
 
 }
 
+func TestPanicHandler(t *testing.T) {
+
+	var recovered *RecoveryError
+
+	custom := errors.New("custom panic error")
+
+	handler := func(wu WorkUnit, err *RecoveryError) error {
+		recovered = err
+		return custom
+	}
+
+	pool := NewLimited(2, WithPanicHandler(handler))
+	defer pool.Close()
+
+	wu := pool.Queue(func(WorkUnit) (interface{}, error) {
+		panic("OMG OMG OMG! something bad happened!")
+	}, WithID("panicky"))
+	wu.Wait()
+
+	Equal(t, wu.Error(), custom)
+	NotEqual(t, recovered, nil)
+	Equal(t, recovered.Value, "OMG OMG OMG! something bad happened!")
+	Equal(t, recovered.UnitID, "panicky")
+	NotEqual(t, len(recovered.Stack), 0)
+	Equal(t, strings.Contains(recovered.Stack[0].Function, ".TestPanicHandler."), true)
+	Equal(t, strings.HasPrefix(recovered.Error(), "ERROR: Work Unit failed due to a recoverable error: 'OMG OMG OMG! something bad happened!'"), true)
+
+	// pool still processing after the panic
+	wu = pool.Queue(func(WorkUnit) (interface{}, error) {
+		return 1, nil
+	})
+	wu.Wait()
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), 1)
+}
+
+func TestPanicAfterContextCancel(t *testing.T) {
+
+	pool := NewLimited(1)
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wu := pool.Queue(func(WorkUnit) (interface{}, error) {
+		time.Sleep(time.Millisecond * 30)
+		panic("after being cancelled")
+	}, WithContext(ctx))
+
+	time.Sleep(time.Millisecond * 10)
+	cancel()
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, errors.Is(wu.Error(), context.Canceled), true)
+
+	// the worker that panicked is replaced
+	wu = pool.Queue(func(WorkUnit) (interface{}, error) {
+		return 1, nil
+	})
+	wu.Wait()
+
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), 1)
+}
+
+func TestWorkerState(t *testing.T) {
+
+	var m sync.Mutex
+	var inits int
+	tornDown := make(map[int]bool)
+
+	pool := NewLimited(2,
+		WithWorkerInit(func() (interface{}, error) {
+			m.Lock()
+			defer m.Unlock()
+			inits++
+			return inits, nil
+		}),
+		WithWorkerTeardown(func(state interface{}) {
+			m.Lock()
+			tornDown[state.(int)] = true
+			m.Unlock()
+		}),
+	)
+	defer pool.Close()
+
+	fn := func(wu WorkUnit) (interface{}, error) {
+		time.Sleep(time.Millisecond * 10)
+		return wu.WorkerState(), nil
+	}
+
+	var res []WorkUnit
+
+	for i := 0; i < 10; i++ {
+		res = append(res, pool.Queue(fn))
+	}
+
+	for _, wu := range res {
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+		Equal(t, wu.Value() == 1 || wu.Value() == 2, true)
+	}
+
+	// worker replaced after panic
+	wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		panic(wu.WorkerState())
+	})
+	wu.Wait()
+
+	var rec *RecoveryError
+	Equal(t, errors.As(wu.Error(), &rec), true)
+
+	time.Sleep(time.Millisecond * 50)
+
+	m.Lock()
+	Equal(t, inits, 3)
+	Equal(t, len(tornDown), 1)
+	Equal(t, tornDown[rec.Value.(int)], true)
+	m.Unlock()
+
+	pool.Close()
+	time.Sleep(time.Millisecond * 50)
+
+	m.Lock()
+	Equal(t, len(tornDown), 3)
+	m.Unlock()
+}
+
+func TestQueueBacklogWithoutGoroutines(t *testing.T) {
+
+	release := make(chan struct{})
+
+	pool := NewLimited(2)
+	defer pool.Close()
+
+	base := runtime.NumGoroutine()
+
+	blocking := func(WorkUnit) (interface{}, error) {
+		<-release
+		return 1, nil
+	}
+
+	res := make([]WorkUnit, 0, 100000)
+
+	for i := 0; i < 100000; i++ {
+		res = append(res, pool.Queue(blocking))
+	}
+
+	// queueing never blocks nor parks a goroutine per unit
+	Equal(t, runtime.NumGoroutine() <= base, true)
+
+	close(release)
+
+	var count int
+
+	for _, wu := range res {
+		wu.Wait()
+		count += wu.Value().(int)
+	}
+
+	Equal(t, count, 100000)
+}
+
+func TestWorkerInitFailure(t *testing.T) {
+
+	var m sync.Mutex
+	var inits int
+	var tornDown []interface{}
+
+	failure := errors.New("no connection")
+
+	pool := NewLimited(1,
+		WithWorkerInit(func() (interface{}, error) {
+			m.Lock()
+			defer m.Unlock()
+
+			inits++
+
+			switch {
+			case inits <= 2:
+				return nil, failure
+			case inits == 3:
+				panic("init panicked")
+			}
+
+			return inits, nil
+		}),
+		WithWorkerTeardown(func(state interface{}) {
+			m.Lock()
+			tornDown = append(tornDown, state)
+			m.Unlock()
+		}),
+	)
+
+	fn := func(wu WorkUnit) (interface{}, error) {
+		return wu.WorkerState(), nil
+	}
+
+	// not retried until there's work, nor is the worker replaced
+	time.Sleep(time.Millisecond * 50)
+
+	m.Lock()
+	Equal(t, inits, 1)
+	m.Unlock()
+
+	// each unit taken retries, once the backoff has passed, and fails
+	// without running while the worker can't be initialized
+	wu := pool.Queue(fn)
+	wu.Wait()
+
+	var initErr *WorkerInitError
+	Equal(t, errors.Is(wu.Error(), ErrWorkerInit), true)
+	Equal(t, errors.As(wu.Error(), &initErr), true)
+	Equal(t, initErr.Err, failure)
+	Equal(t, errors.Is(wu.Error(), failure), true)
+	Equal(t, wu.Value(), nil)
+
+	time.Sleep(time.Millisecond * 50)
+
+	wu = pool.Queue(fn)
+	wu.Wait()
+
+	var rec *RecoveryError
+	Equal(t, errors.Is(wu.Error(), ErrWorkerInit), true)
+	Equal(t, errors.As(wu.Error(), &rec), true)
+	Equal(t, rec.Value, "init panicked")
+
+	time.Sleep(time.Millisecond * 50)
+
+	wu = pool.Queue(fn)
+	wu.Wait()
+
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), 4)
+
+	pool.Close()
+	time.Sleep(time.Millisecond * 20)
+
+	m.Lock()
+	Equal(t, inits, 4)
+	Equal(t, tornDown, []interface{}{4})
+	m.Unlock()
+}
+
+func TestWorkStealing(t *testing.T) {
+
+	var m sync.Mutex
+	var workers int
+
+	pool := NewLimited(2, WithWorkerInit(func() (interface{}, error) {
+		m.Lock()
+		defer m.Unlock()
+		workers++
+		return workers, nil
+	}))
+	defer pool.Close()
+
+	release := make(chan struct{})
+
+	hold := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		<-release
+		return wu.WorkerState(), nil
+	})
+
+	time.Sleep(time.Millisecond * 20)
+
+	var order []int
+	var children []WorkUnit
+
+	child := func(i int) WorkFunc {
+		return func(wu WorkUnit) (interface{}, error) {
+			m.Lock()
+			order = append(order, i)
+			m.Unlock()
+			return wu.WorkerState(), nil
+		}
+	}
+
+	// children run next on the parent's worker, most recently queued first
+	parent := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		for i := 0; i < 5; i++ {
+			children = append(children, pool.Queue(child(i), WithParent(wu)))
+		}
+		return wu.WorkerState(), nil
+	})
+	parent.Wait()
+
+	for _, c := range children {
+		c.Wait()
+		Equal(t, c.Value(), parent.Value())
+	}
+
+	Equal(t, order, []int{4, 3, 2, 1, 0})
+
+	close(release)
+	hold.Wait()
+
+	// idle workers steal children from a busy worker
+	children = children[0:0]
+
+	parent = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+
+		for i := 0; i < 5; i++ {
+			children = append(children, pool.Queue(child(i), WithParent(wu)))
+		}
+
+		for _, c := range children {
+			c.Wait()
+		}
+
+		return wu.WorkerState(), nil
+	})
+	parent.Wait()
+
+	for _, c := range children {
+		NotEqual(t, c.Value(), parent.Value())
+	}
+}
+
+func TestEarliestDeadlineFirst(t *testing.T) {
+
+	pool := NewLimited(1, WithEarliestDeadlineFirst())
+	defer pool.Close()
+
+	release := make(chan struct{})
+
+	pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	time.Sleep(time.Millisecond * 20)
+
+	var m sync.Mutex
+	var order []string
+
+	record := func(name string) WorkFunc {
+		return func(wu WorkUnit) (interface{}, error) {
+			m.Lock()
+			order = append(order, name)
+			m.Unlock()
+			return nil, nil
+		}
+	}
+
+	now := time.Now()
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Minute*2))
+	defer cancel()
+
+	units := []WorkUnit{
+		pool.Queue(record("none-1")),
+		pool.Queue(record("late"), WithDeadline(now.Add(time.Minute*3))),
+		pool.Queue(record("expired"), WithDeadline(now.Add(time.Millisecond*20))),
+		pool.Queue(record("ctx"), WithContext(ctx)),
+		pool.Queue(record("none-2")),
+		pool.Queue(record("soon"), WithDeadline(now.Add(time.Minute))),
+	}
+
+	time.Sleep(time.Millisecond * 50) // let the expired unit's deadline pass
+	close(release)
+
+	for _, wu := range units {
+		wu.Wait()
+	}
+
+	Equal(t, order, []string{"soon", "ctx", "late", "none-1", "none-2"})
+
+	err := units[2].Error()
+
+	var timeout *TimeoutError
+	Equal(t, errors.Is(err, ErrCancelled), true)
+	Equal(t, errors.As(err, &timeout), true)
+	Equal(t, timeout.Deadline, now.Add(time.Millisecond*20))
+	Equal(t, errors.Is(err, context.DeadlineExceeded), true)
+
+	// pending units are cancelled on close and the pool is usable after a reset
+	pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		time.Sleep(time.Millisecond * 50)
+		return nil, nil
+	})
+
+	time.Sleep(time.Millisecond * 20)
+
+	wu := pool.Queue(record("closed"))
+	pool.Close()
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrPoolClosed), true)
+
+	pool.Reset()
+
+	wu = pool.Queue(record("reset"), WithDeadline(time.Now().Add(time.Second)))
+	wu.Wait()
+
+	Equal(t, wu.Error(), nil)
+}
+
 func TestBadWorkerCount(t *testing.T) {
 	PanicMatches(t, func() { NewLimited(0) }, "invalid workers '0'")
 }
+
+func TestLimitedTTL(t *testing.T) {
+
+	pool := NewLimited(1)
+	defer pool.Close()
+
+	release := make(chan struct{})
+
+	blocking := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	var ran int32
+
+	run := func(wu WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&ran, 1)
+		return nil, nil
+	}
+
+	stale := pool.Queue(run, WithTTL(time.Millisecond*20))
+	fresh := pool.Queue(run, WithTTL(time.Minute))
+
+	// expires while waiting behind the blocking unit, without waiting on it
+	stale.Wait()
+
+	var expired *ExpiredError
+	Equal(t, errors.Is(stale.Error(), ErrExpired), true)
+	Equal(t, errors.As(stale.Error(), &expired), true)
+	Equal(t, expired.TTL, time.Millisecond*20)
+	Equal(t, stale.Error().Error(), "ERROR: Work Unit expired, not started within it's TTL of 20ms")
+
+	close(release)
+	blocking.Wait()
+	fresh.Wait()
+
+	Equal(t, fresh.Error(), nil)
+	Equal(t, atomic.LoadInt32(&ran), int32(1))
+
+	// not expired once started
+	wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		time.Sleep(time.Millisecond * 30)
+		return "done", nil
+	}, WithTTL(time.Millisecond*10))
+	wu.Wait()
+
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), "done")
+}
+
+func TestRetries(t *testing.T) {
+
+	pool := NewLimited(2)
+	defer pool.Close()
+
+	errFlaky := errors.New("flaky")
+
+	var attempts int32
+
+	flaky := func(failures int32) WorkFunc {
+		return func(wu WorkUnit) (interface{}, error) {
+			if n := atomic.AddInt32(&attempts, 1); n <= failures {
+				return nil, errFlaky
+			}
+			return "ok", nil
+		}
+	}
+
+	// succeeds on it's third attempt
+	wu := pool.Queue(flaky(2), WithRetries(2))
+	wu.Wait()
+
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), "ok")
+	Equal(t, atomic.LoadInt32(&attempts), int32(3))
+
+	// fails once it's out of retries
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(flaky(3), WithRetries(1))
+	wu.Wait()
+
+	Equal(t, wu.Error(), errFlaky)
+	Equal(t, atomic.LoadInt32(&attempts), int32(2))
+
+	// not retried by default
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(flaky(1))
+	wu.Wait()
+
+	Equal(t, wu.Error(), errFlaky)
+	Equal(t, atomic.LoadInt32(&attempts), int32(1))
+
+	// nor once cancelled
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		wu.Cancel()
+		return nil, errFlaky
+	}, WithRetries(2))
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, atomic.LoadInt32(&attempts), int32(1))
+
+	// panics aren't retried
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		panic("boom")
+	}, WithRetries(2))
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrRecovery), true)
+	Equal(t, atomic.LoadInt32(&attempts), int32(1))
+}
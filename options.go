@@ -0,0 +1,203 @@
+package pool
+
+import "time"
+
+// defaultIdleTimeout is how long an unlimited pool's goroutine is cached
+// waiting for more work before exiting.
+const defaultIdleTimeout = time.Second
+
+// Option configures a Pool instance as it is created.
+type Option func(*options)
+
+// PanicHandler is called when a Work Unit panics while being processed, it
+// receives the Work Unit and the recovery error describing the panic. The returned
+// error becomes the Work Unit's error, so it may be logged and returned as is,
+// replaced by a custom error or the handler may re-panic to crash the program.
+type PanicHandler func(wu WorkUnit, err *RecoveryError) error
+
+// options contains all configurable settings shared by the pool types
+type options struct {
+	panicHandler   PanicHandler
+	workerInit     func() (interface{}, error)
+	workerTeardown func(state interface{})
+	idleTimeout    time.Duration
+	edf            bool
+	capacity       uint
+	rate           float64
+	burst          uint
+	keyLimit       uint
+	failures       uint
+	cooldown       time.Duration
+	adaptiveMin    uint
+	adaptiveMax    uint
+	maxWait        time.Duration
+	shedding       *shedGate // set by newGates(), see shed()
+	deadLetters    DeadLetterHandler
+}
+
+func newOptions(opts []Option) options {
+
+	o := options{
+		idleTimeout: defaultIdleTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithPanicHandler sets the PanicHandler to be called when a Work Unit panics.
+func WithPanicHandler(h PanicHandler) Option {
+	return func(o *options) {
+		o.panicHandler = h
+	}
+}
+
+// WithWorkerInit sets a function run by each worker of a limited pool as it starts,
+// the returned state is kept for the worker's lifetime and is available to every
+// Work Unit the worker processes via WorkUnit.WorkerState(). eg. a DB connection,
+// parser or buffer per worker. While it fails, returning an error or panicking, the
+// worker fails the Work Units it takes with a WorkerInitError, retrying with backoff.
+// NOTE: only applies to limited pools, see NewLimited()
+func WithWorkerInit(fn func() (interface{}, error)) Option {
+	return func(o *options) {
+		o.workerInit = fn
+	}
+}
+
+// WithWorkerTeardown sets a function run with a worker's state, see WithWorkerInit(),
+// as the worker exits, if it was initialized. Workers exit when the pool is Closed or Cancelled, once any
+// Work Unit they're processing has returned, and when replaced after recovering
+// from a panic.
+// NOTE: only applies to limited pools, see NewLimited()
+func WithWorkerTeardown(fn func(state interface{})) Option {
+	return func(o *options) {
+		o.workerTeardown = fn
+	}
+}
+
+// WithIdleTimeout sets how long an unlimited pool's goroutines are parked waiting
+// to be reused for new Work Units once idle, defaults to 1 second. New goroutines are
+// only started when none are idle so concurrency remains unbounded. A duration <= 0
+// disables reuse, starting a new goroutine for every Work Unit.
+// NOTE: only applies to unlimited pools, see New()
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// WithEarliestDeadlineFirst runs a limited pool's pending Work Units in order of deadline, see
+// WithDeadline(), cancelling those whose deadline has passed instead of running them.
+// NOTE: only applies to limited pools, see NewLimited()
+func WithEarliestDeadlineFirst() Option {
+	return func(o *options) {
+		o.edf = true
+	}
+}
+
+// WithCapacity sets the total weight of Work Units, see WithWeight(), the pool processes at
+// once, a unit heavier than the whole capacity runs alone. 0, the default, admits everything.
+func WithCapacity(capacity uint) Option {
+	return func(o *options) {
+		o.capacity = capacity
+	}
+}
+
+// WithRateLimit limits the pool to handing perSecond Work Units to it's workers, in bursts of
+// up to burst after being idle. A rate <= 0, the default, doesn't limit.
+func WithRateLimit(perSecond float64, burst uint) Option {
+	return func(o *options) {
+		o.rate = perSecond
+		o.burst = burst
+	}
+}
+
+// WithKeyLimit limits how many Work Units with the same key, see WithKey(), the pool processes
+// at once eg. per remote host. Units without a key aren't limited, 0, the default, doesn't limit.
+func WithKeyLimit(limit uint) Option {
+	return func(o *options) {
+		o.keyLimit = limit
+	}
+}
+
+// WithCircuitBreaker fails Work Units named by WithBreaker() with a CircuitOpenError for cooldown
+// once failures consecutive units with the name fail, then lets a single trial unit through.
+// 0 failures, the default, disables it.
+func WithCircuitBreaker(failures uint, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.failures = failures
+		o.cooldown = cooldown
+	}
+}
+
+// WithAdaptiveConcurrency adjusts the pool's concurrency between min and max, growing it
+// additively while Work Unit latency stays healthy and cutting it multiplicatively when units
+// fail or slow down. A limited pool's workers still cap it. A max of 0, the default, disables it.
+func WithAdaptiveConcurrency(min, max uint) Option {
+	return func(o *options) {
+		o.adaptiveMin = min
+		o.adaptiveMax = max
+	}
+}
+
+// WithLoadShedding fails Work Units with an OverloadError instead of running them once they've
+// waited longer than maxWait, or as they're queued while expected to. 0, the default, disables it.
+func WithLoadShedding(maxWait time.Duration) Option {
+	return func(o *options) {
+		o.maxWait = maxWait
+	}
+}
+
+// shed reports whether the Work Unit, about to run, has waited too long to be worth running,
+// failing it with an OverloadError if so.
+func (o *options) shed(wu *workUnit) bool {
+
+	if o.shedding == nil {
+		return false
+	}
+
+	if o.shedding.start(wu) {
+		waited := wu.started.Sub(wu.queued)
+		wu.cancelWithError(&OverloadError{Waited: waited, MaxWait: o.maxWait})
+		return true
+	}
+
+	return false
+}
+
+// WithDeadLetter sets the DeadLetterHandler to be called with the Work Units that failed,
+// after all their retries, or panicked, see WithRetries() and NewDeadLetterFile(). Units
+// cancelled, shed or failed by an open circuit breaker didn't fail by their own work and
+// aren't passed to it. It's called by the worker that processed the unit before the unit is
+// done, so should be quick.
+func WithDeadLetter(h DeadLetterHandler) Option {
+	return func(o *options) {
+		o.deadLetters = h
+	}
+}
+
+// deadLetter passes the Work Unit to the DeadLetterHandler, if any, when it failed by it's
+// own work. Called once it's error is set but before it's done, so waiting on the unit
+// waits for it's DeadLetter to be handled.
+func (o *options) deadLetter(wu *workUnit) {
+	if o.deadLetters != nil && wu.err != nil && ownOutcome(wu.err) {
+		o.deadLetters(newDeadLetter(wu))
+	}
+}
+
+// recovered returns the error to assign to a Work Unit that panicked with the value v.
+// NOTE: must be called from the deferred function that recovered so the stack of the
+// panicking goroutine is still intact.
+func (o *options) recovered(wu *workUnit, v interface{}) error {
+
+	err := newRecoveryError(wu.id, v)
+
+	if o.panicHandler != nil {
+		return o.panicHandler(wu, err)
+	}
+
+	return err
+}
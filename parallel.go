@@ -0,0 +1,140 @@
+package pool
+
+import "iter"
+
+// indexed carries a result along with the position of it's input
+type indexed[R any] struct {
+	i int
+	v R
+}
+
+// Map runs fn for each value of in on the pool p, returning the results in the same
+// order as in. The first error to occur cancels the remaining work and is returned.
+// The Batch used internally is completed for you.
+func Map[T, R any](p Pool, in []T, fn func(wu WorkUnit, v T) (R, error)) ([]R, error) {
+	return MapSeq(p, func(yield func(T) bool) {
+		for _, v := range in {
+			if !yield(v) {
+				return
+			}
+		}
+	}, fn)
+}
+
+// MapSeq runs fn for each value of the iterator seq on the pool p, returning the results
+// in the order they were produced by seq. The first error to occur cancels the remaining
+// work, stops iterating seq and is returned. The Batch used internally is completed for you.
+func MapSeq[T, R any](p Pool, seq iter.Seq[T], fn func(wu WorkUnit, v T) (R, error)) ([]R, error) {
+
+	batch := p.Batch()
+	stop := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		// DO NOT FORGET THIS OR GOROUTINES WILL DEADLOCK
+		defer batch.QueueComplete()
+
+		var i int
+
+		for v := range seq {
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			idx := i
+			batch.Queue(func(wu WorkUnit) (interface{}, error) {
+				r, err := fn(wu, v)
+				return indexed[R]{i: idx, v: r}, err
+			})
+			i++
+		}
+	}()
+
+	var results []R
+
+	err := drain(batch, stop, exited, func(wu WorkUnit) {
+
+		r := wu.Value().(indexed[R])
+
+		if r.i >= len(results) {
+			results = append(results, make([]R, r.i-len(results)+1)...)
+		}
+
+		results[r.i] = r.v
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ForEach runs fn for each value received on ch, until it's closed, on the pool p.
+// The first error to occur cancels the remaining work, stops receiving from ch and
+// is returned. The Batch used internally is completed for you.
+func ForEach[T any](p Pool, ch <-chan T, fn func(wu WorkUnit, v T) error) error {
+
+	batch := p.Batch()
+	stop := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		// DO NOT FORGET THIS OR GOROUTINES WILL DEADLOCK
+		defer batch.QueueComplete()
+
+		for {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				batch.Queue(func(wu WorkUnit) (interface{}, error) {
+					return nil, fn(wu, v)
+				})
+
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return drain(batch, stop, exited, func(WorkUnit) {})
+}
+
+// drain calls fn for each successful result of the batch, on the first error
+// the batch is cancelled, stop is closed and the error returned once the
+// remaining results have been drained and the producer has exited, so the
+// input is never read after returning.
+func drain(batch Batch, stop, exited chan struct{}, fn func(wu WorkUnit)) error {
+
+	var first error
+
+	for wu := range batch.Results() {
+
+		if err := wu.Error(); err != nil {
+			if first == nil {
+				first = err
+				close(stop)
+				batch.Cancel()
+			}
+			continue
+		}
+
+		if first == nil {
+			fn(wu)
+		}
+	}
+
+	<-exited
+
+	return first
+}
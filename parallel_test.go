@@ -0,0 +1,146 @@
+package pool
+
+import (
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestMap(t *testing.T) {
+
+	in := make([]int, 100)
+	for i := range in {
+		in[i] = i
+	}
+
+	double := func(wu WorkUnit, v int) (int, error) {
+		time.Sleep(time.Duration(100-v) * time.Microsecond) // finish out of order
+		return v * 2, nil
+	}
+
+	for _, p := range []Pool{NewLimited(4), New()} {
+
+		results, err := Map(p, in, double)
+		Equal(t, err, nil)
+		Equal(t, len(results), 100)
+
+		for i, v := range results {
+			Equal(t, v, i*2)
+		}
+
+		results, err = MapSeq(p, slices.Values(in[:10]), double)
+		Equal(t, err, nil)
+		Equal(t, results, []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18})
+
+		p.Close()
+	}
+}
+
+func TestMapError(t *testing.T) {
+
+	errBad := errors.New("bad value")
+
+	var ran int32
+
+	pool := NewLimited(2)
+	defer pool.Close()
+
+	in := make([]int, 1000)
+	for i := range in {
+		in[i] = i
+	}
+
+	results, err := Map(pool, in, func(wu WorkUnit, v int) (int, error) {
+		atomic.AddInt32(&ran, 1)
+		if v == 5 {
+			return 0, errBad
+		}
+		time.Sleep(time.Millisecond)
+		return v, nil
+	})
+
+	Equal(t, err, errBad)
+	Equal(t, len(results), 0)
+	Equal(t, atomic.LoadInt32(&ran) < 1000, true)
+
+	// stops pulling from the iterator
+	var pulled int
+
+	_, err = MapSeq(pool, func(yield func(int) bool) {
+		for i := 0; i < 1000; i++ {
+			pulled++
+			time.Sleep(time.Millisecond)
+			if !yield(i) {
+				return
+			}
+		}
+	}, func(wu WorkUnit, v int) (int, error) {
+		return 0, errBad
+	})
+
+	Equal(t, err, errBad)
+	Equal(t, pulled < 1000, true)
+}
+
+func TestForEach(t *testing.T) {
+
+	var sum int64
+
+	pool := New()
+	defer pool.Close()
+
+	ch := make(chan int)
+
+	go func() {
+		for i := 1; i <= 100; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+
+	err := ForEach(pool, ch, func(wu WorkUnit, v int) error {
+		atomic.AddInt64(&sum, int64(v))
+		return nil
+	})
+
+	Equal(t, err, nil)
+	Equal(t, sum, int64(5050))
+
+	errBad := errors.New("bad value")
+
+	ch = make(chan int) // never closed
+
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case ch <- i:
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+
+	err = ForEach(pool, ch, func(wu WorkUnit, v int) error {
+		if v == 10 {
+			return errBad
+		}
+		return nil
+	})
+
+	Equal(t, err, errBad)
+}
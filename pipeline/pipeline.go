@@ -0,0 +1,302 @@
+/*
+Package pipeline chains typed stages of work together, each stage running it's
+units of work on a pool with it's own concurrency.
+
+Stages are connected by bounded buffers so a slow stage applies backpressure to
+those before it, and an error or cancellation in any stage ends the whole pipeline.
+
+	pl := pipeline.New(ctx)
+
+	ids := pipeline.From(pl, []int{1, 2, 3})
+	users := pipeline.Then(ids, getUser, pipeline.WithWorkers(4))
+	emails := pipeline.Then(users, sendEmail, pipeline.WithPool(gpool), pipeline.WithBuffer(10))
+
+	results, err := pipeline.Collect(emails)
+
+NOTE: values flow through each stage in the order they complete, not the order
+they were received.
+*/
+package pipeline
+
+import (
+	"context"
+	"sync"
+
+	"gopkg.in/go-playground/pool.v3"
+)
+
+// StageFunc is the function type run for each value received by a stage
+type StageFunc[In, Out any] func(wu pool.WorkUnit, in In) (Out, error)
+
+// Pipeline contains all information for a pipeline of stages
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// New returns a new Pipeline, cancelled when ctx is done
+func New(ctx context.Context) *Pipeline {
+
+	pl := new(Pipeline)
+	pl.ctx, pl.cancel = context.WithCancel(ctx)
+
+	return pl
+}
+
+// Cancel cancels the pipeline, stopping all of it's stages.
+func (pl *Pipeline) Cancel() {
+	pl.fail(context.Canceled)
+}
+
+// fail records the first error to occur and cancels the pipeline
+func (pl *Pipeline) fail(err error) {
+	pl.once.Do(func() {
+		pl.err = err
+		pl.cancel()
+	})
+}
+
+// wait blocks until all stages have exited returning the
+// first error to occur, if any.
+func (pl *Pipeline) wait() error {
+
+	pl.wg.Wait()
+
+	// parent context done without any stage failing
+	if err := pl.ctx.Err(); err != nil {
+		pl.fail(context.Cause(pl.ctx))
+	}
+
+	pl.once.Do(func() {}) // no more errors recorded after this point
+	pl.cancel()
+
+	return pl.err
+}
+
+// Stage is a step of a Pipeline outputting values of type T
+type Stage[T any] struct {
+	pl  *Pipeline
+	out chan T
+}
+
+// Out returns the channel the stage outputs it's values on, it's closed once
+// the stage has finished or the pipeline has been cancelled.
+// NOTE: use Collect() or ForEach() to consume the final stage unless you need the
+// channel directly, they wait for all stages to exit and return the pipeline's error.
+func (s *Stage[T]) Out() <-chan T {
+	return s.out
+}
+
+// From returns a source stage outputting each value of in.
+func From[T any](pl *Pipeline, in []T) *Stage[T] {
+
+	out := make(chan T)
+
+	pl.wg.Add(1)
+	go func() {
+		defer pl.wg.Done()
+		defer close(out)
+
+		for _, v := range in {
+			select {
+			case out <- v:
+			case <-pl.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &Stage[T]{pl: pl, out: out}
+}
+
+// FromChan returns a source stage outputting each value received on in until it's closed.
+func FromChan[T any](pl *Pipeline, in <-chan T) *Stage[T] {
+
+	out := make(chan T)
+
+	pl.wg.Add(1)
+	go func() {
+		defer pl.wg.Done()
+		defer close(out)
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+
+				select {
+				case out <- v:
+				case <-pl.ctx.Done():
+					return
+				}
+
+			case <-pl.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &Stage[T]{pl: pl, out: out}
+}
+
+// StageOption configures a stage
+type StageOption func(*stageConfig)
+
+type stageConfig struct {
+	workers uint
+	buffer  int
+	pool    pool.Pool
+}
+
+// WithWorkers sets the number of values a stage processes concurrently, defaults to 1
+func WithWorkers(workers uint) StageOption {
+	return func(c *stageConfig) {
+		c.workers = workers
+	}
+}
+
+// WithBuffer sets how many output values a stage buffers before blocking, defaults to 0.
+// A stage only receives more values while it has room to output them.
+func WithBuffer(size int) StageOption {
+	return func(c *stageConfig) {
+		c.buffer = size
+	}
+}
+
+// WithPool runs the stage's units of work on the shared pool p instead of the stage's
+// own limited pool, WithWorkers() still limits how many are queued on p at once.
+func WithPool(p pool.Pool) StageOption {
+	return func(c *stageConfig) {
+		c.pool = p
+	}
+}
+
+// Then returns a stage running fn for each value output by s. The first error returned
+// by fn, or unit of work that fails, cancels the pipeline.
+func Then[In, Out any](s *Stage[In], fn StageFunc[In, Out], opts ...StageOption) *Stage[Out] {
+
+	c := stageConfig{workers: 1}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if c.workers == 0 {
+		panic("invalid workers '0'")
+	}
+
+	pl := s.pl
+	out := make(chan Out, c.buffer)
+	p := c.pool
+
+	if p == nil {
+		p = pool.NewLimited(c.workers)
+	}
+
+	pl.wg.Add(1)
+	go func() {
+
+		sem := make(chan struct{}, c.workers)
+
+		var wg sync.WaitGroup
+
+		defer func() {
+			wg.Wait()
+
+			if c.pool == nil {
+				p.Close()
+			}
+
+			close(out)
+			pl.wg.Done()
+		}()
+
+		for {
+			var in In
+			var ok bool
+
+			select {
+			case in, ok = <-s.out:
+				if !ok {
+					return
+				}
+			case <-pl.ctx.Done():
+				return
+			}
+
+			// limits the stage's concurrency and, holding the slot until the
+			// output has been sent, provides backpressure to earlier stages
+			select {
+			case sem <- struct{}{}:
+			case <-pl.ctx.Done():
+				return
+			}
+
+			wu := p.Queue(func(wu pool.WorkUnit) (interface{}, error) {
+				return fn(wu, in)
+			}, pool.WithContext(pl.ctx))
+
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				wu.Wait()
+
+				if err := wu.Error(); err != nil {
+					pl.fail(err)
+					return
+				}
+
+				v, _ := wu.Value().(Out)
+
+				select {
+				case out <- v:
+				case <-pl.ctx.Done():
+				}
+			}()
+		}
+	}()
+
+	return &Stage[Out]{pl: pl, out: out}
+}
+
+// Collect returns all values output by the final stage s once the pipeline has
+// finished, along with the first error to occur in any stage.
+func Collect[T any](s *Stage[T]) ([]T, error) {
+
+	var results []T
+
+	err := ForEach(s, func(v T) error {
+		results = append(results, v)
+		return nil
+	})
+
+	return results, err
+}
+
+// ForEach calls fn for each value output by the final stage s, an error returned
+// by fn cancels the pipeline. Once the pipeline has finished the first error to
+// occur in any stage, or fn, is returned.
+func ForEach[T any](s *Stage[T], fn func(v T) error) error {
+
+	for v := range s.out {
+		if err := fn(v); err != nil {
+			s.pl.fail(err)
+			break
+		}
+	}
+
+	// drain so no stage is left blocked sending
+	for range s.out {
+	}
+
+	return s.pl.wait()
+}
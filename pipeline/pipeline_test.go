@@ -0,0 +1,183 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+	"gopkg.in/go-playground/pool.v3"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestPipeline(t *testing.T) {
+
+	shared := pool.New()
+	defer shared.Close()
+
+	in := make([]int, 100)
+	for i := range in {
+		in[i] = i
+	}
+
+	pl := New(context.Background())
+
+	nums := From(pl, in)
+
+	squares := Then(nums, func(wu pool.WorkUnit, i int) (int, error) {
+		return i * i, nil
+	}, WithWorkers(4), WithBuffer(10))
+
+	strs := Then(squares, func(wu pool.WorkUnit, i int) (string, error) {
+		return strconv.Itoa(i), nil
+	}, WithPool(shared), WithWorkers(8))
+
+	results, err := Collect(strs)
+	Equal(t, err, nil)
+	Equal(t, len(results), 100)
+
+	sort.Slice(results, func(i, j int) bool {
+		a, _ := strconv.Atoi(results[i])
+		b, _ := strconv.Atoi(results[j])
+		return a < b
+	})
+
+	for i, s := range results {
+		Equal(t, s, strconv.Itoa(i*i))
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+
+	errBad := errors.New("bad value")
+
+	var processed int32
+
+	ch := make(chan int)
+
+	go func() {
+		defer close(ch)
+		for i := 0; i < 1000; i++ {
+			select {
+			case ch <- i:
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+
+	pl := New(context.Background())
+
+	nums := FromChan(pl, ch)
+
+	checked := Then(nums, func(wu pool.WorkUnit, i int) (int, error) {
+		if i == 10 {
+			return 0, errBad
+		}
+		return i, nil
+	}, WithWorkers(2))
+
+	last := Then(checked, func(wu pool.WorkUnit, i int) (int, error) {
+		atomic.AddInt32(&processed, 1)
+		return i, nil
+	})
+
+	_, err := Collect(last)
+	Equal(t, errors.Is(err, errBad), true)
+	Equal(t, atomic.LoadInt32(&processed) < 1000, true)
+}
+
+func TestPipelineBackpressure(t *testing.T) {
+
+	var received int32
+
+	release := make(chan struct{})
+
+	in := make([]int, 100)
+
+	pl := New(context.Background())
+
+	first := Then(From(pl, in), func(wu pool.WorkUnit, i int) (int, error) {
+		atomic.AddInt32(&received, 1)
+		return i, nil
+	}, WithWorkers(2), WithBuffer(2))
+
+	second := Then(first, func(wu pool.WorkUnit, i int) (int, error) {
+		<-release
+		return i, nil
+	})
+
+	time.Sleep(time.Millisecond * 100)
+
+	// 1 blocked in the second stage and 1 waiting on it, 2 buffered and
+	// 2 waiting on the buffer, the rest not yet received by the first stage
+	Equal(t, atomic.LoadInt32(&received) <= 6, true)
+
+	close(release)
+
+	results, err := Collect(second)
+	Equal(t, err, nil)
+	Equal(t, len(results), 100)
+	Equal(t, atomic.LoadInt32(&received), int32(100))
+}
+
+func TestPipelineCancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pl := New(ctx)
+
+	stage := Then(From(pl, make([]int, 100)), func(wu pool.WorkUnit, i int) (int, error) {
+		time.Sleep(time.Millisecond * 10)
+		return i, nil
+	})
+
+	var count int
+
+	err := ForEach(stage, func(int) error {
+		count++
+		if count == 5 {
+			cancel()
+		}
+		return nil
+	})
+
+	Equal(t, errors.Is(err, context.Canceled), true)
+	Equal(t, count < 100, true)
+
+	pl = New(context.Background())
+
+	stage = Then(From(pl, make([]int, 100)), func(wu pool.WorkUnit, i int) (int, error) {
+		return i, nil
+	})
+
+	errStop := errors.New("stop")
+
+	err = ForEach(stage, func(int) error {
+		return errStop
+	})
+
+	Equal(t, err, errStop)
+}
+
+func TestBadStageWorkers(t *testing.T) {
+	PanicMatches(t, func() {
+		Then(From(New(context.Background()), []int{}), func(wu pool.WorkUnit, i int) (int, error) {
+			return i, nil
+		}, WithWorkers(0))
+	}, "invalid workers '0'")
+}
@@ -1,10 +1,19 @@
 package pool
 
+import "time"
+
 // Pool contains all information for a pool instance.
 type Pool interface {
 
 	// Queue queues the work to be run, and starts processing immediately
-	Queue(fn WorkFunc) WorkUnit
+	Queue(fn WorkFunc, opts ...UnitOption) WorkUnit
+
+	// QueueHedged queues the work to be run and, each time after passes without a copy
+	// having succeeded, queues another copy up to maxCopies in all. The first copy to
+	// succeed wins and the others are cancelled, discarding their results, so the work
+	// must be idempotent. The returned Work Unit fails with the error of the first copy
+	// to fail only once every copy has failed. An after of 0 queues every copy straight away.
+	QueueHedged(fn WorkFunc, after time.Duration, maxCopies uint, opts ...UnitOption) WorkUnit
 
 	// Reset reinitializes a pool that has been closed/cancelled back to a working
 	// state. if the pool has not been closed/cancelled, nothing happens as the pool
@@ -2,7 +2,9 @@ package pool
 
 import (
 	"os"
+	"sync"
 	"testing"
+	"time"
 )
 
 // NOTES:
@@ -34,3 +36,39 @@ func TestMain(m *testing.M) {
 
 	// teardown
 }
+
+func TestCancelRacingCompletion(t *testing.T) {
+
+	for _, pool := range []Pool{NewLimited(8), New()} {
+
+		var wg sync.WaitGroup
+
+		// cancelled while completing, panicking and cancelling themselves,
+		// done must be closed exactly once whichever wins
+		for i := 0; i < 2000; i++ {
+
+			i := i
+
+			wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+				switch i % 3 {
+				case 0:
+					panic("racing")
+				case 1:
+					wu.Cancel()
+				}
+				return nil, nil
+			})
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(time.Duration(i%5) * time.Microsecond)
+				wu.Cancel()
+				wu.Wait()
+			}()
+		}
+
+		wg.Wait()
+		pool.Close()
+	}
+}
@@ -0,0 +1,159 @@
+package pool
+
+// minQueueSize is the smallest capacity a unitQueue will shrink back down to
+const minQueueSize = 16
+
+// unitQueue is a growable ring buffer deque of pending Work Units so that
+// queued work costs a slot in a slice rather than a blocked goroutine.
+// NOTE: unitQueue is not safe for concurrent use, the owner must guard it.
+type unitQueue struct {
+	buf  []*workUnit
+	head int
+	n    int
+}
+
+// len returns the number of Work Units in the queue
+func (q *unitQueue) len() int {
+	return q.n
+}
+
+// push adds the Work Unit to the back of the queue, growing it if full
+func (q *unitQueue) push(wu *workUnit) {
+
+	if q.n == len(q.buf) {
+		q.resize(max(len(q.buf)*2, minQueueSize))
+	}
+
+	q.buf[(q.head+q.n)%len(q.buf)] = wu
+	q.n++
+}
+
+// pushFront adds the Work Unit to the front of the queue, growing it if full
+func (q *unitQueue) pushFront(wu *workUnit) {
+
+	if q.n == len(q.buf) {
+		q.resize(max(len(q.buf)*2, minQueueSize))
+	}
+
+	q.head = (q.head - 1 + len(q.buf)) % len(q.buf)
+	q.buf[q.head] = wu
+	q.n++
+}
+
+// pop removes and returns the Work Unit at the front of the queue,
+// or nil if the queue is empty.
+func (q *unitQueue) pop() *workUnit {
+
+	if q.n == 0 {
+		return nil
+	}
+
+	wu := q.buf[q.head]
+	q.buf[q.head] = nil // release for garbage collection
+	q.head = (q.head + 1) % len(q.buf)
+	q.n--
+	q.shrink()
+
+	return wu
+}
+
+// popBack removes and returns the Work Unit at the back of the queue,
+// or nil if the queue is empty.
+func (q *unitQueue) popBack() *workUnit {
+
+	if q.n == 0 {
+		return nil
+	}
+
+	i := (q.head + q.n - 1) % len(q.buf)
+	wu := q.buf[i]
+	q.buf[i] = nil // release for garbage collection
+	q.n--
+	q.shrink()
+
+	return wu
+}
+
+// shrink halves the queue once mostly empty so a burst
+// of work doesn't pin memory for a long running pool.
+func (q *unitQueue) shrink() {
+	if len(q.buf) > minQueueSize && q.n < len(q.buf)/4 {
+		q.resize(len(q.buf) / 2)
+	}
+}
+
+func (q *unitQueue) resize(size int) {
+
+	buf := make([]*workUnit, size)
+
+	for i := 0; i < q.n; i++ {
+		buf[i] = q.buf[(q.head+i)%len(q.buf)]
+	}
+
+	q.buf = buf
+	q.head = 0
+}
+
+// unitList is an intrusive doubly linked list of in-flight Work Units, units
+// are unlinked as they finish so a long running pool only references the work
+// it still has in progress.
+// NOTE: unitList is not safe for concurrent use, the owner must guard it.
+type unitList struct {
+	head *workUnit
+	n    int
+}
+
+// len returns the number of Work Units in the list
+func (l *unitList) len() int {
+	return l.n
+}
+
+// add links the Work Unit to the front of the list
+func (l *unitList) add(wu *workUnit) {
+
+	wu.list = l
+	wu.prev = nil
+	wu.next = l.head
+
+	if l.head != nil {
+		l.head.prev = wu
+	}
+
+	l.head = wu
+	l.n++
+}
+
+// remove unlinks the Work Unit, if it's still in the list
+func (l *unitList) remove(wu *workUnit) {
+
+	if wu.list != l {
+		return
+	}
+
+	if wu.prev != nil {
+		wu.prev.next = wu.next
+	} else {
+		l.head = wu.next
+	}
+
+	if wu.next != nil {
+		wu.next.prev = wu.prev
+	}
+
+	wu.list, wu.prev, wu.next = nil, nil, nil
+	l.n--
+}
+
+// clear unlinks all Work Units calling fn for each, most recently added first
+func (l *unitList) clear(fn func(wu *workUnit)) {
+
+	for wu := l.head; wu != nil; {
+		next := wu.next
+		wu.list, wu.prev, wu.next = nil, nil, nil
+		fn(wu)
+		wu = next
+	}
+
+	l.head = nil
+	l.n = 0
+}
@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"testing"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestUnitQueue(t *testing.T) {
+
+	var q unitQueue
+
+	Equal(t, q.pop() == nil, true)
+
+	units := make([]*workUnit, 100)
+
+	for i := range units {
+		units[i] = &workUnit{id: string(rune('a' + i%26))}
+	}
+
+	// wrap around the ring before growing
+	for i := 0; i < 10; i++ {
+		q.push(units[i])
+	}
+
+	for i := 0; i < 5; i++ {
+		Equal(t, q.pop() == units[i], true)
+	}
+
+	for i := 10; i < 100; i++ {
+		q.push(units[i])
+	}
+
+	Equal(t, q.len(), 95)
+	Equal(t, len(q.buf), 128)
+
+	for i := 5; i < 100; i++ {
+		Equal(t, q.pop() == units[i], true)
+	}
+
+	Equal(t, q.len(), 0)
+	Equal(t, len(q.buf), minQueueSize)
+	Equal(t, q.pop() == nil, true)
+}
+
+func TestUnitQueueDeque(t *testing.T) {
+
+	var q unitQueue
+
+	Equal(t, q.popBack() == nil, true)
+
+	a, b, c := &workUnit{id: "a"}, &workUnit{id: "b"}, &workUnit{id: "c"}
+
+	q.push(b)
+	q.pushFront(a)
+	q.push(c)
+
+	Equal(t, q.len(), 3)
+	Equal(t, q.popBack() == c, true)
+	Equal(t, q.pop() == a, true)
+	Equal(t, q.popBack() == b, true)
+	Equal(t, q.len(), 0)
+
+	for i := 0; i < 100; i++ {
+		q.pushFront(&workUnit{id: string(rune('a' + i%26))})
+	}
+
+	Equal(t, q.len(), 100)
+
+	for i := 0; i < 100; i++ {
+		Equal(t, q.popBack().id, string(rune('a'+i%26)))
+	}
+}
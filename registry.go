@@ -0,0 +1,83 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+)
+
+// TaskHandler is the function type registered against a Task name. It receives
+// the Work Unit being processed along with the Task's serialized payload.
+type TaskHandler func(wu WorkUnit, payload []byte) (interface{}, error)
+
+// Task is a serializable descriptor of a unit of work. Unlike a WorkFunc closure
+// it can be persisted or sent elsewhere and is resolved to it's registered
+// TaskHandler, by Name, only when it is about to be run.
+type Task struct {
+	Name    string `json:"name"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// Registry maps Task names to their TaskHandlers.
+type Registry struct {
+	handlers map[string]TaskHandler
+	m        sync.RWMutex
+}
+
+// NewRegistry returns a new, empty, Task registry instance
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]TaskHandler),
+	}
+}
+
+// Register registers the TaskHandler h to be run for Tasks with the given name.
+// Register panics if the name is blank, h is nil or a handler has already been
+// registered for that name.
+func (r *Registry) Register(name string, h TaskHandler) {
+
+	if len(name) == 0 {
+		panic("invalid task name ''")
+	}
+
+	if h == nil {
+		panic(fmt.Sprintf("invalid nil handler for task '%s'", name))
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if _, ok := r.handlers[name]; ok {
+		panic(fmt.Sprintf("task '%s' already registered", name))
+	}
+
+	r.handlers[name] = h
+}
+
+// Lookup returns the TaskHandler registered for name and whether one was found.
+func (r *Registry) Lookup(name string) (TaskHandler, bool) {
+	r.m.RLock()
+	h, ok := r.handlers[name]
+	r.m.RUnlock()
+	return h, ok
+}
+
+// WorkFunc returns a WorkFunc for the Task that can be queued onto any Pool.
+// The Task's handler is resolved when the WorkFunc is run, not when it is created,
+// if no handler has been registered the Work Unit's error is a TaskNotRegisteredError.
+func (r *Registry) WorkFunc(t Task) WorkFunc {
+	return func(wu WorkUnit) (interface{}, error) {
+
+		h, ok := r.Lookup(t.Name)
+		if !ok {
+			return nil, &TaskNotRegisteredError{Name: t.Name}
+		}
+
+		return h(wu, t.Payload)
+	}
+}
+
+// Queue queues the Task to be run on the provided Pool. Unlike queueing it's WorkFunc
+// the Task is recorded against the Work Unit, see DeadLetter.
+func (r *Registry) Queue(p Pool, t Task, opts ...UnitOption) WorkUnit {
+	return p.Queue(r.WorkFunc(t), append([]UnitOption{withTask(t)}, opts...)...)
+}
@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestRegistry(t *testing.T) {
+
+	r := NewRegistry()
+	r.Register("echo", func(wu WorkUnit, payload []byte) (interface{}, error) {
+		return string(payload), nil
+	})
+
+	// round trip the descriptor to prove it's serializable
+	b, err := json.Marshal(Task{Name: "echo", Payload: []byte("hello")})
+	Equal(t, err, nil)
+
+	var task Task
+	err = json.Unmarshal(b, &task)
+	Equal(t, err, nil)
+
+	pool := NewLimited(2)
+	defer pool.Close()
+
+	for _, p := range []Pool{pool, New()} {
+		wu := r.Queue(p, task)
+		wu.Wait()
+		Equal(t, wu.Error(), nil)
+		Equal(t, wu.Value(), "hello")
+	}
+
+	wu := r.Queue(pool, Task{Name: "missing"})
+	wu.Wait()
+	NotEqual(t, wu.Error(), nil)
+
+	var e *TaskNotRegisteredError
+	Equal(t, errors.As(wu.Error(), &e), true)
+	Equal(t, errors.Is(wu.Error(), ErrTaskNotRegistered), true)
+	Equal(t, e.Name, "missing")
+	Equal(t, e.Error(), "ERROR: Task 'missing' has not been registered")
+
+	// resolved at run time, not when the WorkFunc is created
+	fn := r.WorkFunc(Task{Name: "late"})
+	r.Register("late", func(wu WorkUnit, payload []byte) (interface{}, error) {
+		return 1, nil
+	})
+
+	wu = pool.Queue(fn)
+	wu.Wait()
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), 1)
+}
+
+func TestRegistryBadRegistration(t *testing.T) {
+
+	h := func(wu WorkUnit, payload []byte) (interface{}, error) { return nil, nil }
+
+	r := NewRegistry()
+	r.Register("task", h)
+
+	PanicMatches(t, func() { r.Register("", h) }, "invalid task name ''")
+	PanicMatches(t, func() { r.Register("nil", nil) }, "invalid nil handler for task 'nil'")
+	PanicMatches(t, func() { r.Register("task", h) }, "task 'task' already registered")
+}
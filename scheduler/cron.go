@@ -0,0 +1,218 @@
+package scheduler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError is returned by ParseCron() for an invalid cron expression
+type ParseError struct {
+	Expr   string
+	Reason string
+}
+
+// Error returns the error message
+func (e *ParseError) Error() string {
+	return "invalid cron expression '" + e.Expr + "': " + e.Reason
+}
+
+// cronField is the range of values and names allowed in a field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // index + min is the value of the name
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSchedule is a Schedule matching the times of a cron expression,
+// each field being a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// whether day of month and day of week are unrestricted, when both are
+	// restricted a day matching either runs, as with the standard cron
+	domStar, dowStar bool
+}
+
+// ParseCron parses a standard 5 field cron expression; minute, hour, day of month,
+// month and day of week. Fields may be a '*', a value, a range 'a-b', a step '*/n'
+// or 'a-b/n' or a comma separated list of those. Months and days of the week may also
+// be given by their first three letters, eg. 'jan' or 'mon', and Sunday is 0 or 7.
+// The macros @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly are
+// also supported. Times are matched in the location of the time passed to Next().
+func ParseCron(expr string) (Schedule, error) {
+
+	spec := strings.ToLower(strings.TrimSpace(expr))
+
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+
+	if len(fields) != len(cronFields) {
+		return nil, &ParseError{Expr: expr, Reason: "expected 5 fields, found " + strconv.Itoa(len(fields))}
+	}
+
+	var bits [5]uint64
+
+	for i, field := range fields {
+
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, &ParseError{Expr: expr, Reason: err.Error()}
+		}
+
+		bits[i] = b
+	}
+
+	// 7 is also Sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+
+		rng, step, hasStep := strings.Cut(part, "/")
+
+		lo, hi := f.min, f.max
+
+		if rng != "*" {
+			var err error
+
+			first, last, isRange := strings.Cut(rng, "-")
+
+			if lo, err = parseCronValue(first, f); err != nil {
+				return 0, err
+			}
+
+			hi = lo
+
+			if isRange {
+				if hi, err = parseCronValue(last, f); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max // 'a/n' is from a to the end of the range
+			}
+
+			if hi < lo {
+				return 0, errors.New("invalid " + f.name + " range '" + rng + "'")
+			}
+		}
+
+		n := 1
+
+		if hasStep {
+			var err error
+			if n, err = strconv.Atoi(step); err != nil || n <= 0 {
+				return 0, errors.New("invalid " + f.name + " step '" + step + "'")
+			}
+		}
+
+		for v := lo; v <= hi; v += n {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(s string, f cronField) (int, error) {
+
+	for i, name := range f.names {
+		if s == name {
+			return i + f.min, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.New("invalid " + f.name + " '" + s + "'")
+	}
+
+	return v, nil
+}
+
+// Next returns the first time after t matching the schedule, or the zero time
+// if there is none within the next five years eg. '0 0 30 2 *'.
+func (c *cronSchedule) Next(t time.Time) time.Time {
+
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
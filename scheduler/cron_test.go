@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestCronNext(t *testing.T) {
+
+	// a Wednesday
+	from := time.Date(2024, time.January, 10, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 10, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, time.January, 11, 3, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2024, time.January, 11, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, time.January, 10, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon", time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 6-7", time.Date(2024, time.January, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)}, // either day matches
+		{"0,20 11 * jan,mar *", time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}}, // never
+	}
+
+	for _, tt := range tests {
+
+		sched, err := ParseCron(tt.expr)
+		Equal(t, err, nil)
+
+		next := sched.Next(from)
+		if !next.Equal(tt.expected) {
+			t.Errorf("%s: expected %s got %s", tt.expr, tt.expected, next)
+		}
+	}
+}
+
+func TestCronParseErrors(t *testing.T) {
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"* * * *", "invalid cron expression '* * * *': expected 5 fields, found 4"},
+		{"60 * * * *", "invalid cron expression '60 * * * *': invalid minute '60'"},
+		{"* 5-1 * * *", "invalid cron expression '* 5-1 * * *': invalid hour range '5-1'"},
+		{"* * 0 * *", "invalid cron expression '* * 0 * *': invalid day of month '0'"},
+		{"* * * foo *", "invalid cron expression '* * * foo *': invalid month 'foo'"},
+		{"*/0 * * * *", "invalid cron expression '*/0 * * * *': invalid minute step '0'"},
+		{"@often", "invalid cron expression '@often': expected 5 fields, found 1"},
+	}
+
+	for _, tt := range tests {
+
+		_, err := ParseCron(tt.expr)
+		NotEqual(t, err, nil)
+		Equal(t, err.Error(), tt.expected)
+
+		var pe *ParseError
+		Equal(t, errors.As(err, &pe), true)
+		Equal(t, pe.Expr, tt.expr)
+	}
+}
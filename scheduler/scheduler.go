@@ -0,0 +1,316 @@
+/*
+Package scheduler queues a unit of work onto a pool periodically, on a fixed
+interval or a cron expression, until stopped.
+
+	job := scheduler.Every(gpool, time.Minute, refreshCache, scheduler.WithJitter(time.Second*5))
+	defer job.Stop()
+
+	job, err := scheduler.Cron(gpool, "0 3 * * *", compact, scheduler.WithOverlap(scheduler.CancelPrevious))
+	if err != nil {
+		// invalid expression
+	}
+	defer job.Stop()
+
+Runs are queued onto the pool like any other unit of work, so the pool's own limits
+apply, and what happens when a run is due while the previous one is still going is
+controlled by the job's Overlap policy.
+*/
+package scheduler
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"gopkg.in/go-playground/pool.v3"
+)
+
+// Schedule returns the next time a job should run after t
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// interval is a Schedule running every d
+type interval time.Duration
+
+func (d interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(d))
+}
+
+// Overlap is the policy applied when a run is due while a previous one is still going
+type Overlap int
+
+// Overlap policies
+const (
+	// Skip does not queue the run, waiting for the next one instead
+	Skip Overlap = iota
+
+	// QueueAnyway queues the run regardless, so runs may overlap
+	QueueAnyway
+
+	// CancelPrevious cancels the runs still going and waits for them to return before
+	// queueing the new one, so runs never overlap. Cancellation is cooperative, a run's
+	// WorkFunc finds out by checking IsCancelled(), after which it can't be cancelled,
+	// and however long it takes to return is waited for.
+	CancelPrevious
+)
+
+// Option configures a Job
+type Option func(*config)
+
+type config struct {
+	overlap Overlap
+	jitter  time.Duration
+	ctx     context.Context
+}
+
+// WithOverlap sets the job's overlap policy, defaults to Skip
+func WithOverlap(o Overlap) Option {
+	return func(c *config) {
+		c.overlap = o
+	}
+}
+
+// WithJitter delays each run by a random duration in [0, d) so jobs scheduled
+// for the same time, across instances of a service, don't all run at once.
+func WithJitter(d time.Duration) Option {
+	return func(c *config) {
+		c.jitter = d
+	}
+}
+
+// WithContext stops the job once ctx is done, as if Stop() had been called
+func WithContext(ctx context.Context) Option {
+	return func(c *config) {
+		c.ctx = ctx
+	}
+}
+
+// Job is a WorkFunc scheduled to run periodically on a pool
+type Job struct {
+	pool    pool.Pool
+	fn      pool.WorkFunc
+	sched   Schedule
+	cfg     config
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	wg      sync.WaitGroup
+	m       sync.Mutex
+	running map[*run]struct{}
+}
+
+// run is a single run of a Job, over once it's WorkFunc has returned or, if it never
+// started, once it's Work Unit is done eg. cancelled while still queued. A Work Unit
+// is done as soon as it's cancelled, so can't be waited on for the WorkFunc to return.
+type run struct {
+	unit      pool.WorkUnit
+	over      chan struct{}
+	started   bool
+	abandoned bool
+	m         sync.Mutex
+}
+
+// begin reports whether the run's WorkFunc may start, it may not once abandoned
+func (r *run) begin() bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.abandoned {
+		return false
+	}
+
+	r.started = true
+	return true
+}
+
+// abandon reports whether the run was abandoned, it's Work Unit being done without
+// it's WorkFunc having started, which won't now start.
+func (r *run) abandon() bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.started {
+		return false
+	}
+
+	r.abandoned = true
+	return true
+}
+
+// Every returns a Job queueing fn onto p every d, starting d from now
+func Every(p pool.Pool, d time.Duration, fn pool.WorkFunc, opts ...Option) *Job {
+
+	if d <= 0 {
+		panic("invalid interval '" + d.String() + "'")
+	}
+
+	return New(p, interval(d), fn, opts...)
+}
+
+// Cron returns a Job queueing fn onto p at the times matched by the cron expression
+// expr, see ParseCron(), or an error if expr is invalid.
+func Cron(p pool.Pool, expr string, fn pool.WorkFunc, opts ...Option) (*Job, error) {
+
+	sched, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(p, sched, fn, opts...), nil
+}
+
+// New returns a Job queueing fn onto p at the times returned by sched
+func New(p pool.Pool, sched Schedule, fn pool.WorkFunc, opts ...Option) *Job {
+
+	cfg := config{ctx: context.Background()}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	j := &Job{
+		pool:    p,
+		fn:      fn,
+		sched:   sched,
+		cfg:     cfg,
+		done:    make(chan struct{}),
+		running: make(map[*run]struct{}),
+	}
+
+	j.ctx, j.cancel = context.WithCancel(cfg.ctx)
+
+	go j.run()
+
+	return j
+}
+
+// Stop stops the job, cancelling any runs still going, and waits for it to exit
+// including for the WorkFuncs of those runs to return. It is safe to call more than once.
+func (j *Job) Stop() {
+	j.cancel()
+	<-j.done
+}
+
+// Done returns a channel that's closed once the job has stopped
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+func (j *Job) run() {
+
+	defer func() {
+		// runs are queued with the job's context so are cancelled along with it,
+		// waiting for their WorkFuncs to return
+		j.wg.Wait()
+		close(j.done)
+	}()
+
+	next := j.sched.Next(time.Now())
+
+	for {
+		// the schedule never matches again
+		if next.IsZero() {
+			<-j.ctx.Done()
+			return
+		}
+
+		delay := time.Until(next)
+
+		if j.cfg.jitter > 0 {
+			delay += rand.N(j.cfg.jitter)
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-j.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		j.fire()
+
+		// scheduled from the previous due time so intervals don't drift,
+		// unless it's fallen behind eg. the system was suspended
+		next = j.sched.Next(next)
+
+		if now := time.Now(); next.Before(now) {
+			next = j.sched.Next(now)
+		}
+	}
+}
+
+// fire queues a run of the job applying it's overlap policy
+func (j *Job) fire() {
+
+	j.m.Lock()
+
+	if len(j.running) > 0 {
+		switch j.cfg.overlap {
+		case Skip:
+			j.m.Unlock()
+			return
+
+		case CancelPrevious:
+			previous := make([]*run, 0, len(j.running))
+
+			for r := range j.running {
+				r.unit.Cancel()
+				previous = append(previous, r)
+			}
+
+			j.m.Unlock()
+
+			for _, r := range previous {
+				select {
+				case <-r.over:
+				case <-j.ctx.Done():
+					return
+				}
+			}
+
+			j.m.Lock()
+		}
+	}
+
+	r := &run{over: make(chan struct{})}
+
+	j.running[r] = struct{}{}
+	j.wg.Add(1)
+
+	r.unit = j.pool.Queue(func(wu pool.WorkUnit) (interface{}, error) {
+
+		if !r.begin() {
+			return nil, nil
+		}
+
+		defer j.end(r)
+
+		return j.fn(wu)
+	}, pool.WithContext(j.ctx))
+
+	j.m.Unlock()
+
+	go func() {
+		r.unit.Wait()
+
+		if r.abandon() {
+			j.end(r)
+		}
+	}()
+}
+
+// end stops tracking the run once it's over
+func (j *Job) end(r *run) {
+
+	close(r.over)
+
+	j.m.Lock()
+	delete(j.running, r)
+	j.m.Unlock()
+
+	j.wg.Done()
+}
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "gopkg.in/go-playground/assert.v1"
+	"gopkg.in/go-playground/pool.v3"
+)
+
+// NOTES:
+// - Run "go test" to run tests
+// - Run "gocov test | gocov report" to report on test converage by file
+// - Run "gocov test | gocov annotate -" to report on all code and functions, those ,marked with "MISS" were never called
+//
+// or
+//
+// -- may be a good idea to change to output path to somewherelike /tmp
+// go test -coverprofile cover.out && go tool cover -html=cover.out -o cover.html
+//
+
+func TestEvery(t *testing.T) {
+
+	var runs int32
+
+	p := pool.New()
+	defer p.Close()
+
+	job := Every(p, time.Millisecond*10, func(wu pool.WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&runs, 1)
+		return nil, nil
+	})
+
+	time.Sleep(time.Millisecond * 105)
+	job.Stop()
+
+	n := atomic.LoadInt32(&runs)
+	Equal(t, n >= 5 && n <= 10, true)
+
+	// no more runs once stopped
+	time.Sleep(time.Millisecond * 30)
+	Equal(t, atomic.LoadInt32(&runs), n)
+
+	select {
+	case <-job.Done():
+	default:
+		t.Fatal("job not done after Stop()")
+	}
+
+	job.Stop() // safe to call again
+}
+
+func TestOverlap(t *testing.T) {
+
+	for _, overlap := range []Overlap{Skip, QueueAnyway, CancelPrevious} {
+
+		var started, running, overlapped int32
+
+		p := pool.New()
+
+		// takes longer than the interval, without checking if it's cancelled
+		job := Every(p, time.Millisecond*10, func(wu pool.WorkUnit) (interface{}, error) {
+
+			atomic.AddInt32(&started, 1)
+
+			if atomic.AddInt32(&running, 1) > 1 {
+				atomic.StoreInt32(&overlapped, 1)
+			}
+
+			time.Sleep(time.Millisecond * 25)
+			atomic.AddInt32(&running, -1)
+
+			return nil, nil
+		}, WithOverlap(overlap))
+
+		time.Sleep(time.Millisecond * 100)
+		job.Stop()
+
+		// every run has returned once stopped
+		Equal(t, atomic.LoadInt32(&running), int32(0))
+
+		if overlap == QueueAnyway {
+			Equal(t, atomic.LoadInt32(&started) >= 5, true)
+			Equal(t, atomic.LoadInt32(&overlapped), int32(1))
+		} else {
+			Equal(t, atomic.LoadInt32(&started) >= 2, true)
+			Equal(t, atomic.LoadInt32(&overlapped), int32(0))
+		}
+
+		p.Close()
+	}
+}
+
+func TestCancelPrevious(t *testing.T) {
+
+	p := pool.New()
+	defer p.Close()
+
+	units := make(chan pool.WorkUnit, 10)
+
+	var returned int32
+
+	job := Every(p, time.Millisecond*10, func(wu pool.WorkUnit) (interface{}, error) {
+		units <- wu
+		time.Sleep(time.Millisecond * 25)
+		atomic.AddInt32(&returned, 1)
+		return nil, nil
+	}, WithOverlap(CancelPrevious))
+
+	first := <-units
+	<-units // the second run cancels the first, once it's returned
+
+	Equal(t, first.IsCancelled(), true)
+	Equal(t, atomic.LoadInt32(&returned) >= 1, true)
+
+	job.Stop()
+}
+
+func TestStopCancelsRuns(t *testing.T) {
+
+	p := pool.New()
+	defer p.Close()
+
+	units := make(chan pool.WorkUnit, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var returned int32
+
+	job := Every(p, time.Millisecond*10, func(wu pool.WorkUnit) (interface{}, error) {
+		units <- wu
+		time.Sleep(time.Millisecond * 50)
+
+		if wu.IsCancelled() {
+			atomic.StoreInt32(&returned, 1)
+		}
+
+		return nil, nil
+	}, WithContext(ctx))
+
+	wu := <-units
+
+	start := time.Now()
+
+	cancel()
+	<-job.Done()
+
+	Equal(t, time.Since(start) < time.Millisecond*500, true)
+	Equal(t, wu.IsCancelled(), true)
+
+	// not done until the cancelled run has returned, having found out it was
+	Equal(t, atomic.LoadInt32(&returned), int32(1))
+}
+
+func TestJitter(t *testing.T) {
+
+	var runs int32
+
+	p := pool.New()
+	defer p.Close()
+
+	job := Every(p, time.Millisecond*10, func(wu pool.WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&runs, 1)
+		return nil, nil
+	}, WithJitter(time.Millisecond*20))
+
+	time.Sleep(time.Millisecond * 105)
+	job.Stop()
+
+	// each run delayed by up to 20ms
+	n := atomic.LoadInt32(&runs)
+	Equal(t, n >= 2 && n <= 10, true)
+}
+
+func TestCron(t *testing.T) {
+
+	p := pool.New()
+	defer p.Close()
+
+	_, err := Cron(p, "* * *", func(wu pool.WorkUnit) (interface{}, error) {
+		return nil, nil
+	})
+	NotEqual(t, err, nil)
+
+	job, err := Cron(p, "@daily", func(wu pool.WorkUnit) (interface{}, error) {
+		return nil, nil
+	})
+	Equal(t, err, nil)
+
+	job.Stop()
+}
+
+func TestBadInterval(t *testing.T) {
+	PanicMatches(t, func() {
+		Every(pool.New(), 0, func(wu pool.WorkUnit) (interface{}, error) {
+			return nil, nil
+		})
+	}, "invalid interval '0s'")
+}
@@ -1,28 +1,34 @@
 package pool
 
 import (
-	"fmt"
-	"math"
-	"runtime"
 	"sync"
+	"time"
 )
 
 var _ Pool = new(unlimitedPool)
 
 // unlimitedPool contains all information for an unlimited pool instance.
 type unlimitedPool struct {
-	units  []*workUnit
+	opts   options
+	units  unitList // in-flight Work Units, for bulk cancellation
+	idle   chan *workUnit
 	cancel chan struct{}
+	gates  []gate             // see admission.go
+	admit  func(wu *workUnit) // passes a Work Unit through the gates to dispatch()
 	closed bool
 	m      sync.Mutex
 }
 
-// New returns a new unlimited pool instance
-func New() Pool {
+// New returns a new unlimited pool instance, goroutines are started as needed
+// and cached for reuse once idle, see WithIdleTimeout().
+func New(opts ...Option) Pool {
 
 	p := &unlimitedPool{
-		units: make([]*workUnit, 0, 4), // init capacity to 4, assuming if using pool, then probably a few have at least that many and will reduce array resizes
+		opts: newOptions(opts),
 	}
+
+	p.gates, p.admit = newGates(&p.opts, p.dispatch, p.retire)
+
 	p.initialize()
 
 	return p
@@ -30,70 +36,160 @@ func New() Pool {
 
 func (p *unlimitedPool) initialize() {
 
+	p.idle = make(chan *workUnit)
 	p.cancel = make(chan struct{})
 	p.closed = false
 }
 
 // Queue queues the work to be run, and starts processing immediately
-func (p *unlimitedPool) Queue(fn WorkFunc) WorkUnit {
+func (p *unlimitedPool) Queue(fn WorkFunc, opts ...UnitOption) WorkUnit {
 
 	w := &workUnit{
-		done: make(chan struct{}),
-		fn:   fn,
+		done:   make(chan struct{}),
+		fn:     fn,
+		weight: 1,
+		queued: time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(w)
 	}
 
+	w.watchContext()
+	w.watchTTL()
+
+	// held back until every configured gate lets it through, see newGates()
+	p.admit(w)
+
+	return w
+}
+
+// dispatch hands the Work Unit off to a goroutine to be run
+func (p *unlimitedPool) dispatch(w *workUnit) {
+
 	p.m.Lock()
 
 	if p.closed {
-		w.err = &ErrPoolClosed{s: errClosed}
-		// if w.cancelled.Load() == nil {
-		close(w.done)
-		// }
 		p.m.Unlock()
-		return w
+		w.cancelWithError(&PoolClosedError{})
+		p.retire(w)
+		return
+	}
+
+	p.units.add(w)
+
+	// hand off to an idle goroutine if one is parked
+	// otherwise fire up a new one, keeping the pool unlimited
+	select {
+	case p.idle <- w:
+	default:
+		go p.worker(w, p.idle, p.cancel)
 	}
 
-	p.units = append(p.units, w)
-	go func(w *workUnit) {
+	p.m.Unlock()
+}
+
+// passing idle and cancel channels to worker() to avoid any potential race condition
+// with a Reset() reinitializing them
+func (p *unlimitedPool) worker(w *workUnit, idle chan *workUnit, cancel chan struct{}) {
 
-		defer func(w *workUnit) {
-			if err := recover(); err != nil {
+	if p.opts.idleTimeout <= 0 {
+		p.run(w)
+		return
+	}
 
-				trace := make([]byte, 1<<16)
-				n := runtime.Stack(trace, true)
+	timer := time.NewTimer(p.opts.idleTimeout)
+	defer timer.Stop()
 
-				s := fmt.Sprintf(errRecovery, err, string(trace[:int(math.Min(float64(n), float64(7000)))]))
+	for {
+		p.run(w)
 
-				w.cancelled.Store(struct{}{})
-				w.err = &ErrRecovery{s: s}
-				close(w.done)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
 			}
-		}(w)
+		}
+		timer.Reset(p.opts.idleTimeout)
+
+		// park until more work arrives or idle for too long
+		select {
+		case w = <-idle:
+		case <-timer.C:
+			return
+		case <-cancel:
+			return
+		}
+	}
+}
 
-		// support for individual WorkUnit cancellation
-		// and batch job cancellation
-		if w.cancelled.Load() == nil {
-			val, err := w.fn(w)
+func (p *unlimitedPool) run(w *workUnit) {
 
-			w.writing.Store(struct{}{})
+	defer p.finished(w)
 
-			// need to check again in case the WorkFunc cancelled this unit of work
-			// otherwise we'll have a race condition
-			if w.cancelled.Load() == nil && w.cancelling.Load() == nil {
+	defer func(w *workUnit) {
+		if r := recover(); r != nil {
 
-				w.value, w.err = val, err
+			// committed so it can't be cancelled from here on, but may have
+			// been while running, eg. by it's context, and already be done
+			w.writing.Store(struct{}{})
 
-				// who knows where the Done channel is being listened to on the other end
-				// don't want this to block just because caller is waiting on another unit
-				// of work to be done first so we use close
+			if w.finish() {
+				w.err = p.opts.recovered(w, r)
+				p.opts.deadLetter(w)
 				close(w.done)
 			}
+
+			w.release()
 		}
 	}(w)
 
+	// support for individual WorkUnit cancellation
+	// and batch job cancellation
+	// and expiry of it's TTL, see WithTTL()
+	if w.cancelled.Load() == nil && w.begin() {
+		w.started = time.Now()
+
+		if p.opts.shed(w) {
+			return
+		}
+
+		val, err := w.run()
+
+		w.writing.Store(struct{}{})
+		w.release()
+
+		// may have been cancelled while running, including by
+		// the WorkFunc itself, in which case it's already done
+		if w.finish() {
+
+			w.value, w.err = val, err
+			p.opts.deadLetter(w)
+
+			// who knows where the Done channel is being listened to on the other end
+			// don't want this to block just because caller is waiting on another unit
+			// of work to be done first so we use close
+			close(w.done)
+		}
+	}
+}
+
+// finished stops tracking the Work Unit once processed or skipped so
+// the pool doesn't hold a reference to it, nor it's value or error.
+func (p *unlimitedPool) finished(w *workUnit) {
+	p.m.Lock()
+	p.units.remove(w)
 	p.m.Unlock()
 
-	return w
+	p.retire(w)
+}
+
+// retire releases anything held by the Work Unit while in the pool, once
+// processed or skipped.
+func (p *unlimitedPool) retire(w *workUnit) {
+	for _, g := range p.gates {
+		g.release(w)
+	}
 }
 
 // Reset reinitializes a pool that has been closed/cancelled back to a working state.
@@ -110,6 +206,11 @@ func (p *unlimitedPool) Reset() {
 
 	// cancelled the pool, not closed it, pool will be usable after calling initialize().
 	p.initialize()
+
+	for _, g := range p.gates {
+		g.reopen()
+	}
+
 	p.m.Unlock()
 }
 
@@ -121,15 +222,18 @@ func (p *unlimitedPool) closeWithError(err error) {
 		close(p.cancel)
 		p.closed = true
 
-		// clear out array values for garbage collection, but reuse array just in case going to reuse
-		// go in reverse order to try and cancel as many as possbile
-		// one at end are less likely to have run than those at the beginning
-		for i := len(p.units) - 1; i >= 0; i-- {
-			p.units[i].cancelWithError(err)
-			p.units[i] = nil
+		// first so no more are admitted, those in-flight
+		// are released as their goroutines skip them
+		for _, g := range p.gates {
+			g.close(err)
 		}
 
-		p.units = p.units[0:0]
+		// unlink all for garbage collection, most recently queued first
+		// to try and cancel as many as possbile, ones at the end are
+		// less likely to have run than those at the beginning
+		p.units.clear(func(wu *workUnit) {
+			wu.cancelWithError(err)
+		})
 	}
 
 	p.m.Unlock()
@@ -140,7 +244,7 @@ func (p *unlimitedPool) closeWithError(err error) {
 // call Reset() to reinitialize the pool for use.
 func (p *unlimitedPool) Cancel() {
 
-	err := &ErrCancelled{s: errCancelled}
+	err := &CancelledError{}
 	p.closeWithError(err)
 }
 
@@ -149,10 +253,17 @@ func (p *unlimitedPool) Cancel() {
 // call Reset() to reinitialize the pool for use.
 func (p *unlimitedPool) Close() {
 
-	err := &ErrPoolClosed{s: errClosed}
+	err := &CancelledError{Cause: &PoolClosedError{}}
 	p.closeWithError(err)
 }
 
+// QueueHedged queues the work to be run, queueing another copy each time after passes without
+// one having succeeded up to maxCopies in all. The first copy to succeed wins and the others
+// are cancelled.
+func (p *unlimitedPool) QueueHedged(fn WorkFunc, after time.Duration, maxCopies uint, opts ...UnitOption) WorkUnit {
+	return queueHedged(p, fn, after, maxCopies, opts)
+}
+
 // Batch creates a new Batch object for queueing Work Units separate from any others
 // that may be running on the pool. Grouping these Work Units together allows for individual
 // Cancellation of the Batch Work Units without affecting anything else running on the pool
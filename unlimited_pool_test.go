@@ -1,7 +1,12 @@
 package pool
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -94,9 +99,11 @@ func TestUnlimitedCancel(t *testing.T) {
 		wu.Wait()
 
 		if wu.Error() != nil {
-			_, ok := wu.Error().(*ErrCancelled)
+			var cancelled *CancelledError
+			ok := errors.As(wu.Error(), &cancelled)
 			if !ok {
-				_, ok = wu.Error().(*ErrPoolClosed)
+				var closed *PoolClosedError
+				ok = errors.As(wu.Error(), &closed)
 				if ok {
 					Equal(t, wu.Error().Error(), "ERROR: Work Unit added/run after the pool had been closed or cancelled")
 				}
@@ -192,3 +199,263 @@ func TestUnlimitedPanicRecovery(t *testing.T) {
 	NotEqual(t, wrk.Error(), nil)
 	Equal(t, wrk.Error().Error()[0:90], "ERROR: Work Unit failed due to a recoverable error: 'OMG OMG OMG! something bad happened!'")
 }
+
+func TestUnlimitedPanicHandler(t *testing.T) {
+
+	var recovered *RecoveryError
+
+	custom := errors.New("custom panic error")
+
+	handler := func(wu WorkUnit, err *RecoveryError) error {
+		recovered = err
+		return custom
+	}
+
+	pool := New(WithPanicHandler(handler))
+	defer pool.Close()
+
+	wu := pool.Queue(func(WorkUnit) (interface{}, error) {
+		panic("OMG OMG OMG! something bad happened!")
+	}, WithID("panicky"))
+	wu.Wait()
+
+	Equal(t, wu.Error(), custom)
+	NotEqual(t, recovered, nil)
+	Equal(t, recovered.Value, "OMG OMG OMG! something bad happened!")
+	Equal(t, recovered.UnitID, "panicky")
+	NotEqual(t, len(recovered.Stack), 0)
+	Equal(t, strings.Contains(recovered.Stack[0].Function, ".TestUnlimitedPanicHandler."), true)
+	Equal(t, strings.HasPrefix(recovered.Error(), "ERROR: Work Unit failed due to a recoverable error: 'OMG OMG OMG! something bad happened!'"), true)
+
+	// pool still processing after the panic
+	wu = pool.Queue(func(WorkUnit) (interface{}, error) {
+		return 1, nil
+	})
+	wu.Wait()
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), 1)
+}
+
+func TestUnlimitedGoroutineReuse(t *testing.T) {
+
+	pool := New(WithIdleTimeout(time.Millisecond * 200))
+	defer pool.Close()
+
+	base := runtime.NumGoroutine()
+
+	run := func() {
+
+		release := make(chan struct{})
+
+		var res []WorkUnit
+
+		for i := 0; i < 10; i++ {
+			res = append(res, pool.Queue(func(WorkUnit) (interface{}, error) {
+				<-release
+				return nil, nil
+			}))
+		}
+
+		time.Sleep(time.Millisecond * 20)
+		Equal(t, runtime.NumGoroutine() <= base+10, true)
+
+		close(release)
+
+		for _, wu := range res {
+			wu.Wait()
+		}
+	}
+
+	run()
+
+	// idle goroutines picked up the work instead of new ones being started
+	run()
+
+	// and exited once idle for long enough
+	time.Sleep(time.Millisecond * 400)
+	Equal(t, runtime.NumGoroutine() <= base, true)
+}
+
+func TestUnlimitedSoakMemoryFlat(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping soak test in short mode")
+	}
+
+	pool := New()
+	defer pool.Close()
+
+	payload := func(WorkUnit) (interface{}, error) {
+		return make([]byte, 1024), nil
+	}
+
+	run := func(n int) {
+
+		res := make([]WorkUnit, 0, 1000)
+
+		for i := 0; i < n; i++ {
+			res = append(res, pool.Queue(payload))
+
+			if len(res) == cap(res) {
+				for _, wu := range res {
+					wu.Wait()
+				}
+				res = res[0:0]
+			}
+		}
+
+		for _, wu := range res {
+			wu.Wait()
+		}
+	}
+
+	heap := func() uint64 {
+		var ms runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+		return ms.HeapAlloc
+	}
+
+	// warm up
+	run(10000)
+	before := heap()
+
+	// ~200MB of results, all of which must be released
+	run(200000)
+	after := heap()
+
+	up := pool.(*unlimitedPool)
+	up.m.Lock()
+	Equal(t, up.units.len(), 0)
+	up.m.Unlock()
+
+	Equal(t, after < before+(4<<20), true)
+}
+
+func TestUnlimitedTTL(t *testing.T) {
+
+	pool := New(WithCapacity(1))
+	defer pool.Close()
+
+	release := make(chan struct{})
+
+	blocking := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	var ran int32
+
+	// expires while waiting for capacity
+	stale := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&ran, 1)
+		return nil, nil
+	}, WithTTL(time.Millisecond*20))
+
+	stale.Wait()
+	Equal(t, errors.Is(stale.Error(), ErrExpired), true)
+
+	close(release)
+	blocking.Wait()
+
+	wu := pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		return nil, nil
+	}, WithTTL(time.Minute))
+	wu.Wait()
+
+	Equal(t, wu.Error(), nil)
+	Equal(t, atomic.LoadInt32(&ran), int32(0))
+}
+
+func TestUnlimitedRetries(t *testing.T) {
+
+	pool := New()
+	defer pool.Close()
+
+	errFlaky := errors.New("flaky")
+
+	var attempts int32
+
+	flaky := func(failures int32) WorkFunc {
+		return func(wu WorkUnit) (interface{}, error) {
+			if n := atomic.AddInt32(&attempts, 1); n <= failures {
+				return nil, errFlaky
+			}
+			return "ok", nil
+		}
+	}
+
+	// succeeds on it's third attempt
+	wu := pool.Queue(flaky(2), WithRetries(2))
+	wu.Wait()
+
+	Equal(t, wu.Error(), nil)
+	Equal(t, wu.Value(), "ok")
+	Equal(t, atomic.LoadInt32(&attempts), int32(3))
+
+	// fails once it's out of retries
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(flaky(3), WithRetries(1))
+	wu.Wait()
+
+	Equal(t, wu.Error(), errFlaky)
+	Equal(t, atomic.LoadInt32(&attempts), int32(2))
+
+	// not retried by default
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(flaky(1))
+	wu.Wait()
+
+	Equal(t, wu.Error(), errFlaky)
+	Equal(t, atomic.LoadInt32(&attempts), int32(1))
+
+	// nor once cancelled
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		wu.Cancel()
+		return nil, errFlaky
+	}, WithRetries(2))
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, atomic.LoadInt32(&attempts), int32(1))
+
+	// panics aren't retried
+	atomic.StoreInt32(&attempts, 0)
+
+	wu = pool.Queue(func(wu WorkUnit) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		panic("boom")
+	}, WithRetries(2))
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrRecovery), true)
+	Equal(t, atomic.LoadInt32(&attempts), int32(1))
+}
+
+func TestUnlimitedPanicAfterContextCancel(t *testing.T) {
+
+	pool := New()
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wu := pool.Queue(func(WorkUnit) (interface{}, error) {
+		time.Sleep(time.Millisecond * 30)
+		panic("after being cancelled")
+	}, WithContext(ctx))
+
+	time.Sleep(time.Millisecond * 10)
+	cancel()
+	wu.Wait()
+
+	Equal(t, errors.Is(wu.Error(), ErrCancelled), true)
+	Equal(t, errors.Is(wu.Error(), context.Canceled), true)
+
+	// give the panicking goroutine time to recover
+	time.Sleep(time.Millisecond * 40)
+}
@@ -0,0 +1,185 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// localQueue is a worker's own deque of pending Work Units. The owning worker
+// takes from the front while idle workers steal from the back, children queued
+// from a running Work Unit, see WithParent(), are pushed to the front so they're
+// run next by the same worker while it's caches are still warm.
+type localQueue struct {
+	queue  unitQueue
+	closed bool
+	m      sync.Mutex
+	sq     *stealQueues
+}
+
+// stealQueues contains the local queues of one generation of a limited pool's
+// workers, replaced each time the pool is Reset() after a Close() or Cancel().
+type stealQueues struct {
+	pool    *limitedPool
+	locals  []*localQueue
+	next    atomic.Uint64 // round robin position for work queued from outside a worker
+	pending atomic.Int64  // number of Work Units across all local queues
+	idle    atomic.Int32  // number of workers waiting for work
+	closed  bool          // guarded by pool.m
+}
+
+func newStealQueues(p *limitedPool) *stealQueues {
+
+	sq := &stealQueues{
+		pool:   p,
+		locals: make([]*localQueue, p.workers),
+	}
+
+	for i := range sq.locals {
+		sq.locals[i] = &localQueue{sq: sq}
+	}
+
+	return sq
+}
+
+// push adds the Work Unit to the local queue of the worker running it's parent, if
+// any, otherwise spreads it across the workers, returning false if the queues have
+// been closed. Neither blocks, the queues grow to hold any backlog.
+func (sq *stealQueues) push(wu *workUnit) bool {
+
+	if wu.parent != nil && wu.parent.local != nil && wu.parent.local.sq == sq {
+		return sq.pushLocal(wu.parent.local, wu, true)
+	}
+
+	return sq.pushNext(wu)
+}
+
+// pushLocal adds the Work Unit to the local queue, to the front if it is the child of a
+// Work Unit running on it's worker, returning false if the queues have been closed.
+func (sq *stealQueues) pushLocal(lq *localQueue, wu *workUnit, child bool) bool {
+
+	lq.m.Lock()
+
+	if lq.closed {
+		lq.m.Unlock()
+		return false
+	}
+
+	if child {
+		lq.queue.pushFront(wu)
+	} else {
+		lq.queue.push(wu)
+	}
+
+	lq.m.Unlock()
+
+	sq.pending.Add(1)
+
+	// only contend on the pool lock when there are idle workers to wake
+	if sq.idle.Load() > 0 {
+		sq.pool.m.Lock()
+		sq.pool.cond.Signal()
+		sq.pool.m.Unlock()
+	}
+
+	return true
+}
+
+// pushNext adds the Work Unit to the next local queue in round robin order.
+func (sq *stealQueues) pushNext(wu *workUnit) bool {
+	return sq.pushLocal(sq.locals[sq.next.Add(1)%uint64(len(sq.locals))], wu, false)
+}
+
+// take returns the next Work Unit for the worker owning the local queue at index i,
+// from it's own queue first and then stolen from the others. It blocks while there
+// is no work and returns nil once the queues have been closed.
+func (sq *stealQueues) take(i int) *workUnit {
+
+	for {
+		if wu := sq.pop(i); wu != nil {
+			return wu
+		}
+
+		p := sq.pool
+		p.m.Lock()
+
+		sq.idle.Add(1)
+
+		for sq.pending.Load() <= 0 && !sq.closed {
+			p.cond.Wait()
+		}
+
+		sq.idle.Add(-1)
+		closed := sq.closed
+		p.m.Unlock()
+
+		if closed {
+			return nil
+		}
+	}
+}
+
+// pop returns the next Work Unit without blocking, skipping those cancelled
+func (sq *stealQueues) pop(i int) *workUnit {
+
+	n := len(sq.locals)
+
+	for j := 0; j < n; j++ {
+
+		lq := sq.locals[(i+j)%n]
+
+		for {
+			lq.m.Lock()
+
+			var wu *workUnit
+
+			if j == 0 {
+				wu = lq.queue.pop()
+			} else {
+				wu = lq.queue.popBack() // steal
+			}
+
+			lq.m.Unlock()
+
+			if wu == nil {
+				break
+			}
+
+			sq.pending.Add(-1)
+
+			// support for individual WorkUnit cancellation
+			// and batch job cancellation
+			if wu.cancelled.Load() == nil {
+				wu.local = sq.locals[i]
+				return wu
+			}
+
+			sq.pool.retire(wu)
+		}
+	}
+
+	return nil
+}
+
+// close closes all local queues calling fn for each Work Unit still pending.
+// NOTE: pool.m must be held
+func (sq *stealQueues) close(fn func(wu *workUnit)) {
+
+	sq.closed = true
+
+	for _, lq := range sq.locals {
+
+		lq.m.Lock()
+		lq.closed = true
+
+		for wu := lq.queue.pop(); wu != nil; wu = lq.queue.pop() {
+			fn(wu)
+		}
+
+		lq.m.Unlock()
+	}
+
+	sq.pending.Store(0)
+
+	// wake all idle workers so they exit
+	sq.pool.cond.Broadcast()
+}
@@ -1,6 +1,11 @@
 package pool
 
-import "sync/atomic"
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
 
 // WorkUnit contains a single uint of works values
 type WorkUnit interface {
@@ -22,34 +27,261 @@ type WorkUnit interface {
 	// NOTE: After Checking IsCancelled(), if it returns false the
 	// Work Unit can no longer be cancelled and will use your returned values.
 	IsCancelled() bool
+
+	// ID returns the caller provided identifier of the Work Unit, if any.
+	ID() string
+
+	// WorkerState returns the state of the worker processing the Work Unit,
+	// see WithWorkerInit(), or nil if there is none.
+	WorkerState() interface{}
+}
+
+// UnitOption configures an individual Work Unit as it is queued.
+type UnitOption func(*workUnit)
+
+// WithID sets a caller provided identifier on the Work Unit, used to recognize
+// the unit across runs eg. by Batch Checkpoint() and Resume().
+func WithID(id string) UnitOption {
+	return func(wu *workUnit) {
+		wu.id = id
+	}
+}
+
+// WithParent marks the Work Unit as a child of the running Work Unit parent,
+// typically queued from within parent's WorkFunc. Limited pools queue children
+// on the local queue of the worker running the parent so recursive, fan-out,
+// work stays on the same worker unless idle workers steal it.
+func WithParent(parent WorkUnit) UnitOption {
+	return func(wu *workUnit) {
+		wu.parent, _ = parent.(*workUnit)
+	}
+}
+
+// WithContext ties the Work Unit to ctx, if ctx is done before the Work Unit has been
+// committed to processing it is cancelled with a CancelledError wrapping the context's
+// cause, or a TimeoutError when the context's deadline was exceeded.
+func WithContext(ctx context.Context) UnitOption {
+	return func(wu *workUnit) {
+		wu.ctx = ctx
+	}
+}
+
+// WithDeadline sets the time the Work Unit must start processing by, defaulting to the
+// deadline of it's context if any, see WithContext(). Pools scheduling earliest deadline
+// first, see WithEarliestDeadlineFirst(), run units in order of deadline and cancel those
+// whose deadline has passed instead of running them, other pools ignore it.
+func WithDeadline(d time.Time) UnitOption {
+	return func(wu *workUnit) {
+		wu.deadline = d
+	}
+}
+
+// WithWeight sets the Work Unit's cost, eg. memory in MB or API quota points, counted against
+// the capacity of pools created with WithCapacity(), defaults to 1. Other pools ignore it.
+func WithWeight(weight uint) UnitOption {
+	return func(wu *workUnit) {
+		wu.weight = weight
+	}
+}
+
+// WithKey sets the key the Work Unit's concurrency is limited by in pools created with
+// WithKeyLimit(), eg. the remote host or customer it's for. Other pools ignore it.
+func WithKey(key string) UnitOption {
+	return func(wu *workUnit) {
+		wu.key = key
+	}
+}
+
+// WithBreaker sets the name of the circuit breaker the Work Unit's outcome counts towards
+// in pools created with WithCircuitBreaker(), eg. the downstream service it calls. Other
+// pools ignore it.
+func WithBreaker(name string) UnitOption {
+	return func(wu *workUnit) {
+		wu.breaker = name
+	}
+}
+
+// WithTTL sets the Work Unit's time-to-live, if no worker has started it within ttl of
+// being queued it's failed with an ExpiredError and never runs, eg. for cache refreshes
+// that are stale once a backlog clears.
+func WithTTL(ttl time.Duration) UnitOption {
+	return func(wu *workUnit) {
+		wu.ttl = ttl
+	}
+}
+
+// WithRetries sets how many times the Work Unit is retried when it's WorkFunc returns an
+// error, straight away and by the same worker, defaults to 0. Panics aren't retried.
+func WithRetries(n uint) UnitOption {
+	return func(wu *workUnit) {
+		wu.retries = n
+	}
 }
 
+// withTask records the Task the Work Unit was queued for, see Registry.Queue()
+func withTask(t Task) UnitOption {
+	return func(wu *workUnit) {
+		wu.task = &t
+	}
+}
+
+// unitID returns the ID the UnitOptions would assign to a Work Unit
+func unitID(opts []UnitOption) string {
+
+	wu := new(workUnit)
+
+	for _, opt := range opts {
+		opt(wu)
+	}
+
+	return wu.id
+}
+
+// states of a Work Unit's start, deciding the race between a worker starting it and it's TTL
+const (
+	unitPending int32 = iota
+	unitStarted
+	unitExpired
+)
+
 var _ WorkUnit = new(workUnit)
 
 // workUnit contains a single unit of works values
 type workUnit struct {
-	value      interface{}
-	err        error
-	done       chan struct{}
-	fn         WorkFunc
-	cancelled  atomic.Value
-	cancelling atomic.Value
-	writing    atomic.Value
+	id        string
+	state     interface{}
+	parent    *workUnit
+	local     *localQueue // the queue of the limited pool worker processing the unit
+	ctx       context.Context
+	deadline  time.Time
+	weight    uint
+	admitted  bool // weight reserved by the pool's capacityGate, guarded by it's lock
+	key       string
+	keyed     bool // counted against it's key by the pool's keyGate, guarded by it's lock
+	breaker   string
+	limited   bool // counted in-flight by the pool's adaptiveGate, guarded by it's lock
+	shedding  bool // counted as waiting to run by the pool's shedGate, guarded by it's lock
+	task      *Task
+	retries   uint
+	failures  []Attempt // the failed attempts retried so far
+	queued    time.Time
+	started   time.Time // when first picked up by a worker, for the shedGate's wait to run
+	attempted time.Time // when the latest attempt started, for the adaptiveGate's latency
+	ttl       time.Duration
+	start     atomic.Int32                // unitPending until started or expired, whichever is first
+	expiry    atomic.Pointer[time.Timer]  // stored while the TTL may expire
+	stopCtx   atomic.Pointer[func() bool] // stored while the context callback may be running
+	value     interface{}
+	err       error
+	done      chan struct{}
+	fn        WorkFunc
+	cancelled atomic.Value
+	finished  atomic.Bool // outcome claimed, see finish()
+	writing   atomic.Value
+
+	// intrusive links for tracking the Work Unit while in-flight, see unitList
+	list *unitList
+	prev *workUnit
+	next *workUnit
 }
 
 // Cancel cancels this specific unit of work, if not already committed to processing.
 func (wu *workUnit) Cancel() {
-	wu.cancelWithError(&ErrCancelled{s: errCancelled})
+	wu.cancelWithError(&CancelledError{})
 }
 
-func (wu *workUnit) cancelWithError(err error) {
+// watchContext cancels the Work Unit once it's context, if any, is done.
+// NOTE: must be called before the Work Unit is handed off for processing
+func (wu *workUnit) watchContext() {
+
+	if wu.ctx == nil {
+		return
+	}
+
+	cancel := func() {
+
+		cause := context.Cause(wu.ctx)
+
+		if errors.Is(wu.ctx.Err(), context.DeadlineExceeded) {
+			deadline, _ := wu.ctx.Deadline()
+			cause = &TimeoutError{Deadline: deadline, err: cause}
+		}
+
+		wu.cancelWithError(&CancelledError{Cause: cause})
+	}
+
+	if wu.ctx.Err() != nil {
+		cancel()
+		return
+	}
+
+	stop := context.AfterFunc(wu.ctx, cancel)
+	wu.stopCtx.Store(&stop)
+}
+
+// run calls the Work Unit's WorkFunc, retrying when it fails up to it's
+// retries unless cancelled, see WithRetries().
+func (wu *workUnit) run() (value interface{}, err error) {
+
+	for {
+		started := time.Now()
+		wu.attempted = started
+
+		value, err = wu.fn(wu)
+
+		if err == nil || uint(len(wu.failures)) >= wu.retries || wu.cancelled.Load() != nil {
+			return
+		}
+
+		wu.failures = append(wu.failures, newAttempt(started, err))
+	}
+}
 
-	wu.cancelling.Store(struct{}{})
+// watchTTL expires the Work Unit if it's not started within it's TTL, see WithTTL()
+func (wu *workUnit) watchTTL() {
 
-	if wu.writing.Load() == nil && wu.cancelled.Load() == nil {
+	if wu.ttl <= 0 {
+		return
+	}
+
+	wu.expiry.Store(time.AfterFunc(wu.ttl, func() {
+		if wu.start.CompareAndSwap(unitPending, unitExpired) {
+			wu.cancelWithError(&ExpiredError{TTL: wu.ttl})
+		}
+	}))
+}
+
+// begin marks the Work Unit as started by a worker, returning false
+// if it's TTL expired first in which case it must not be run.
+func (wu *workUnit) begin() bool {
+	return wu.start.CompareAndSwap(unitPending, unitStarted)
+}
+
+// release frees any resources held while the Work Unit was pending
+func (wu *workUnit) release() {
+	if stop := wu.stopCtx.Load(); stop != nil {
+		(*stop)()
+	}
+
+	if t := wu.expiry.Load(); t != nil {
+		t.Stop()
+	}
+}
+
+// finish claims the Work Unit's outcome for the caller, which must then set it and close done,
+// returning false if it's already been claimed eg. by cancelling the unit while it was running.
+// Cancellation, completion and panic recovery all go through it so done is closed only once.
+func (wu *workUnit) finish() bool {
+	return wu.finished.CompareAndSwap(false, true)
+}
+
+func (wu *workUnit) cancelWithError(err error) {
+
+	if wu.writing.Load() == nil && wu.finish() {
 		wu.cancelled.Store(struct{}{})
 		wu.err = err
 		close(wu.done)
+		wu.release()
 	}
 }
 
@@ -75,3 +307,14 @@ func (wu *workUnit) IsCancelled() bool {
 	wu.writing.Store(struct{}{}) // ensure that after this check we are committed as cannot be cancelled if not aalready
 	return wu.cancelled.Load() != nil
 }
+
+// ID returns the caller provided identifier of the Work Unit, if any.
+func (wu *workUnit) ID() string {
+	return wu.id
+}
+
+// WorkerState returns the state of the worker processing the Work Unit,
+// see WithWorkerInit(), or nil if there is none.
+func (wu *workUnit) WorkerState() interface{} {
+	return wu.state
+}